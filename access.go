@@ -0,0 +1,68 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"path"
+
+	"github.com/google/go-querystring/query"
+	"golang.org/x/oauth2"
+)
+
+const apiPathAccesses = "accesses"
+
+// Access represents an access history event.
+type Access struct {
+	ID         string `json:"id"`
+	Action     string `json:"action"`
+	DeviceType string `json:"device_type"`
+	DeviceName string `json:"device_name"`
+	AccessedAt string `json:"accessed_at"`
+	Akerun     struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		ImageURL string `json:"image_url"`
+	} `json:"akerun"`
+	User struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		ImageURL string `json:"image_url"`
+	} `json:"user"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
+}
+
+// AccessList represents a list of access history events.
+type AccessList struct {
+	Accesses []Access `json:"accesses"`
+}
+
+// AccessesParameter represents the parameters for GetAccesses method.
+type AccessesParameter struct {
+	Limit          uint32   `url:"limit,omitempty"`
+	AkerunIds      []string `url:"akerun_ids[],omitempty"`
+	UserIds        []string `url:"user_ids[],omitempty"`
+	DateTimeAfter  string   `url:"datetime_after,omitempty"`
+	DateTimeBefore string   `url:"datetime_before,omitempty"`
+	IdAfter        string   `url:"id_after,omitempty"`
+	IdBefore       string   `url:"id_before,omitempty"`
+}
+
+// GetAccesses returns the access history of an organization.
+func (c *Client) GetAccesses(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	params AccessesParameter,
+) (*AccessList, error) {
+	var result AccessList
+	v, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	err = c.callVersion(ctx, OpGetAccesses, path.Join(apiPathOrganizations, organizationId, apiPathAccesses), http.MethodGet, oauth2Token, v, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
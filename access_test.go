@@ -0,0 +1,45 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_GetAccesses(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check that the request has the correct path and parameters
+		assert.Equal(t, "/v3/organizations/org1/accesses", r.URL.Path)
+		assert.Equal(t, "2024-01-01T00:00:00Z", r.URL.Query().Get("datetime_after"))
+
+		// Write a sample response
+		_, err := w.Write([]byte(`{"accesses":[{"id":"1","action":"unlock","device_type":"nfc_outside","accessed_at":"2024-01-01T09:00:00Z","akerun":{"id":"A1"},"user":{"id":"user1","name":"Test User"}}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	client := NewClient(config)
+
+	token := &oauth2.Token{AccessToken: "test_token"}
+	params := AccessesParameter{DateTimeAfter: "2024-01-01T00:00:00Z"}
+	accesses, err := client.GetAccesses(context.Background(), token, "org1", params)
+
+	// Check that the response was parsed correctly
+	assert.NoError(t, err)
+	assert.Len(t, accesses.Accesses, 1)
+	assert.Equal(t, "unlock", accesses.Accesses[0].Action)
+	assert.Equal(t, "user1", accesses.Accesses[0].User.ID)
+}
@@ -2,6 +2,7 @@ package akerun
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -40,6 +41,78 @@ type Akerun struct {
 		ID                string `json:"id"`
 		BatteryPercentage int    `json:"battery_percentage"`
 	} `json:"door_sensor"`
+	// UnknownBatteries reports the battery levels that the API returned as null or left out.
+	// Their BatteryPercentage fields are 0.
+	UnknownBatteries UnknownBatteries `json:"-"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
+}
+
+// UnknownBatteries represents which battery levels of an Akerun and its peripherals are unknown.
+type UnknownBatteries struct {
+	Lock             bool
+	NFCReaderInside  bool
+	NFCReaderOutside bool
+	DoorSensor       bool
+}
+
+// batteryLevels holds the battery levels of an Akerun to tell null and missing levels from 0.
+type batteryLevels struct {
+	BatteryPercentage *int          `json:"battery_percentage"`
+	NFCReaderInside   deviceBattery `json:"nfc_reader_inside"`
+	NFCReaderOutside  deviceBattery `json:"nfc_reader_outside"`
+	DoorSensor        deviceBattery `json:"door_sensor"`
+}
+
+// deviceBattery holds the battery level of a peripheral.
+type deviceBattery struct {
+	BatteryPercentage *int `json:"battery_percentage"`
+}
+
+// unknownBatteries returns the battery levels that are missing from the encoded Akerun data.
+func unknownBatteries(data []byte) (UnknownBatteries, error) {
+	var levels *batteryLevels
+	if err := json.Unmarshal(data, &levels); err != nil || levels == nil {
+		return UnknownBatteries{}, err
+	}
+	return UnknownBatteries{
+		Lock:             levels.BatteryPercentage == nil,
+		NFCReaderInside:  levels.NFCReaderInside.BatteryPercentage == nil,
+		NFCReaderOutside: levels.NFCReaderOutside.BatteryPercentage == nil,
+		DoorSensor:       levels.DoorSensor.BatteryPercentage == nil,
+	}, nil
+}
+
+// nullify sets the unknown battery levels in the encoded Akerun data to null.
+func (u UnknownBatteries) nullify(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if u.Lock {
+		fields["battery_percentage"] = json.RawMessage("null")
+	}
+	devices := map[string]bool{
+		"nfc_reader_inside":  u.NFCReaderInside,
+		"nfc_reader_outside": u.NFCReaderOutside,
+		"door_sensor":        u.DoorSensor,
+	}
+	for name, unknown := range devices {
+		if !unknown {
+			continue
+		}
+		var device map[string]json.RawMessage
+		if err := json.Unmarshal(fields[name], &device); err != nil {
+			return nil, err
+		}
+		device["battery_percentage"] = json.RawMessage("null")
+		byt, err := json.Marshal(device)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = byt
+	}
+	return json.Marshal(fields)
 }
 
 type AkerunList struct {
@@ -61,7 +134,7 @@ func (c *Client) GetAkeruns(ctx context.Context, oauth2Token *oauth2.Token, orga
 	}
 
 	url := fmt.Sprintf("organizations/%s/akeruns", organizationId)
-	err = c.callVersion(ctx, url, http.MethodGet, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpGetAkeruns, url, http.MethodGet, oauth2Token, v, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -16,6 +16,8 @@ type AkerunGroup struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 	Memo string `json:"memo"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
 }
 
 type AkerunGroupDetailed struct {
@@ -27,26 +29,21 @@ type AkerunGroupDetailed struct {
 		Name     string `json:"name"`
 		ImageURL string `json:"image_url"`
 	} `json:"akeruns"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
 }
 
 type AkerunGroupList struct {
 	AkerunGroups []AkerunGroup `json:"akerun_groups"`
 }
 
-type akerunGroupRow struct {
-	AkerunGroup AkerunGroup `json:"akerun_group"`
-}
-type akerunGroupDetailedRow struct {
-	AkerunGroup AkerunGroupDetailed `json:"akerun_group"`
-}
-
 func (c *Client) GetAkerunGroups(
 	ctx context.Context,
 	oauth2Token *oauth2.Token,
 	organizationId string,
 ) (*AkerunGroupList, error) {
 	var result AkerunGroupList
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup), http.MethodGet, oauth2Token, nil, nil, &result)
+	err := c.callVersion(ctx, OpGetAkerunGroups, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup), http.MethodGet, oauth2Token, nil, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -59,12 +56,12 @@ func (c *Client) GetAkerunGroup(
 	organizationId string,
 	akerunGroupId string,
 ) (*AkerunGroupDetailed, error) {
-	var result akerunGroupDetailedRow
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId), http.MethodGet, oauth2Token, nil, nil, &result)
+	var result AkerunGroupDetailed
+	err := c.callVersion(ctx, OpGetAkerunGroup, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId), http.MethodGet, oauth2Token, nil, enveloped("akerun_group", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.AkerunGroup, nil
+	return &result, nil
 }
 
 type AkerunGroupCreateParameter struct {
@@ -78,17 +75,17 @@ func (c *Client) CreateAkerunGroup(
 	organizationId string,
 	params AkerunGroupCreateParameter,
 ) (*AkerunGroup, error) {
-	var result akerunGroupRow
+	var result AkerunGroup
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup), http.MethodPost, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpCreateAkerunGroup, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup), http.MethodPost, oauth2Token, v, enveloped("akerun_group", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.AkerunGroup, nil
+	return &result, nil
 }
 
 type AkerunGroupUpdateParameter struct {
@@ -103,17 +100,17 @@ func (c *Client) UpdateAkerunGroup(
 	akerunGroupId string,
 	params AkerunGroupUpdateParameter,
 ) (*AkerunGroup, error) {
-	var result akerunGroupRow
+	var result AkerunGroup
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId), http.MethodPut, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpUpdateAkerunGroup, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId), http.MethodPut, oauth2Token, v, enveloped("akerun_group", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.AkerunGroup, nil
+	return &result, nil
 }
 
 func (c *Client) DeleteAkerunGroup(
@@ -122,7 +119,7 @@ func (c *Client) DeleteAkerunGroup(
 	organizationId string,
 	akerunGroupId string,
 ) error {
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId), http.MethodDelete, oauth2Token, nil, nil, nil)
+	err := c.callVersion(ctx, OpDeleteAkerunGroup, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId), http.MethodDelete, oauth2Token, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -136,13 +133,13 @@ func (c *Client) AddAkerunToGroup(
 	akerunGroupId string,
 	akerunIds ...string,
 ) error {
-	var result akerunGroupDetailedRow
+	var result AkerunGroupDetailed
 	v := url.Values{}
 	for _, id := range akerunIds {
 		v.Add("akerun_ids[]", id)
 	}
 
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId, "akeruns"), http.MethodPost, oauth2Token, v, nil, &result)
+	err := c.callVersion(ctx, OpAddAkerunToGroup, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId, "akeruns"), http.MethodPost, oauth2Token, v, enveloped("akerun_group", &result))
 	if err != nil {
 		return err
 	}
@@ -156,13 +153,13 @@ func (c *Client) RemoveAkerunFromGroup(
 	akerunGroupId string,
 	akerunIds ...string,
 ) error {
-	var result akerunGroupDetailedRow
+	var result AkerunGroupDetailed
 	v := url.Values{}
 	for _, id := range akerunIds {
 		v.Add("akerun_ids[]", id)
 	}
 
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId, "akeruns"), http.MethodDelete, oauth2Token, v, nil, &result)
+	err := c.callVersion(ctx, OpRemoveAkerunFromGroup, path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId, "akeruns"), http.MethodDelete, oauth2Token, v, enveloped("akerun_group", &result))
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package akerun
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -49,3 +50,20 @@ func TestClient_GetAkeruns(t *testing.T) {
 
 	os.Setenv("AKERUN_API_URL", originalValue)
 }
+
+func TestAkerun_UnknownBatteries(t *testing.T) {
+	data := []byte(`{"id":"A1","battery_percentage":null,"nfc_reader_inside":{"id":"N1","battery_percentage":0},"nfc_reader_outside":{"id":"N2"},"door_sensor":{"id":"W1","battery_percentage":40}}`)
+
+	var a Akerun
+	assert.NoError(t, json.Unmarshal(data, &a))
+	assert.Equal(t, UnknownBatteries{Lock: true, NFCReaderOutside: true}, a.UnknownBatteries)
+	assert.Equal(t, 0, a.NFCReaderInside.BatteryPercentage)
+	assert.Equal(t, 40, a.DoorSensor.BatteryPercentage)
+
+	// Unknown levels stay unknown after a round trip
+	byt, err := json.Marshal(a)
+	assert.NoError(t, err)
+	var again Akerun
+	assert.NoError(t, json.Unmarshal(byt, &again))
+	assert.Equal(t, a, again)
+}
@@ -0,0 +1,230 @@
+// Package attendance builds first-in/last-out attendance reports from the access history of an organization.
+package attendance
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/Hayao0819/go-akerun/internal/listing"
+	"github.com/Hayao0819/go-akerun/internal/wallclock"
+	"golang.org/x/oauth2"
+)
+
+// dateLayout is the layout of Record.Date.
+const dateLayout = "2006-01-02"
+
+// Direction represents whether an access event is an entry or an exit.
+type Direction int
+
+// Directions
+const (
+	// DirectionNone is an event that is neither an entry nor an exit, such as a remote unlock.
+	DirectionNone Direction = iota
+	DirectionIn
+	DirectionOut
+)
+
+// DefaultClassify treats events from devices outside the door, such as "nfc_outside", as entries
+// and events from devices inside, such as "nfc_inside", as exits.
+func DefaultClassify(a akerun.Access) Direction {
+	switch {
+	case strings.Contains(a.DeviceType, "outside"):
+		return DirectionIn
+	case strings.Contains(a.DeviceType, "inside"):
+		return DirectionOut
+	default:
+		return DirectionNone
+	}
+}
+
+// Options represents the options for Build.
+type Options struct {
+	// Location is the time zone of the dates and times in the report. If nil, time.Local is used.
+	Location *time.Location
+	// DayBoundary is the wall clock time at which a day starts, e.g. 4h to count a night shift
+	// until 4 a.m. towards the previous day, also on days with a daylight saving transition.
+	DayBoundary time.Duration
+	// Classify returns the direction of an access event. If nil, DefaultClassify is used.
+	Classify func(akerun.Access) Direction
+	// AssumeExitAfter is how long after an entry without a matching exit the user is assumed to have left,
+	// capped at the end of the day. If zero, such an entry adds nothing to the presence.
+	AssumeExitAfter time.Duration
+}
+
+// Record represents the attendance of a user on a day.
+type Record struct {
+	// Date is the local date of the day, in the form 2006-01-02.
+	Date     string
+	UserID   string
+	UserName string
+	UserCode string
+	// FirstIn is the time of the first entry, or zero if there was none.
+	FirstIn time.Time
+	// LastOut is the time of the last exit, or zero if there was none.
+	LastOut time.Time
+	// Presence is the total time between entries and their exits. WriteCSV and WriteJSON write it in whole seconds.
+	Presence time.Duration
+	// MissingExit reports that an entry had no matching exit on the same day.
+	MissingExit bool
+	// MissingEntry reports that an exit had no matching entry on the same day.
+	MissingEntry bool
+	// Events is the number of entries and exits.
+	Events int
+}
+
+// Report represents an attendance report.
+type Report struct {
+	Location *time.Location
+	// Records are ordered by date, then by user ID.
+	Records []Record
+	// Skipped is the number of access events that were ignored because they had no user,
+	// no parsable time or no direction.
+	Skipped int
+}
+
+// event represents a classified access event.
+type event struct {
+	at        time.Time
+	direction Direction
+}
+
+// dayKey identifies the record of a user on a day.
+type dayKey struct {
+	date   string
+	userId string
+}
+
+// Build builds an attendance report from access events. The names and codes of the users are taken
+// from users, or from the events for users that are not in users.
+func Build(accesses []akerun.Access, users []akerun.User, opts Options) *Report {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	classify := opts.Classify
+	if classify == nil {
+		classify = DefaultClassify
+	}
+
+	byID := map[string]akerun.User{}
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	report := &Report{Location: loc}
+	days := map[dayKey][]event{}
+	names := map[string]string{}
+	for _, a := range accesses {
+		at, err := time.Parse(time.RFC3339, a.AccessedAt)
+		direction := classify(a)
+		if a.User.ID == "" || err != nil || direction == DirectionNone {
+			report.Skipped++
+			continue
+		}
+		at = at.In(loc)
+		key := dayKey{date: wallclock.DayOf(at, opts.DayBoundary).Format(dateLayout), userId: a.User.ID}
+		days[key] = append(days[key], event{at: at, direction: direction})
+		names[a.User.ID] = a.User.Name
+	}
+
+	for key, events := range days {
+		record := Record{Date: key.date, UserID: key.userId, UserName: names[key.userId]}
+		if u, ok := byID[key.userId]; ok {
+			record.UserName = u.Name
+			record.UserCode = u.Code
+		}
+		day, _ := time.ParseInLocation(dateLayout, key.date, loc)
+		end := wallclock.On(day, opts.DayBoundary, loc).AddDate(0, 0, 1)
+		record.tally(events, end, opts.AssumeExitAfter)
+		report.Records = append(report.Records, record)
+	}
+
+	sort.Slice(report.Records, func(i, j int) bool {
+		a, b := report.Records[i], report.Records[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.UserID < b.UserID
+	})
+	return report
+}
+
+// tally computes the record from the events of the day, which ends at end.
+// Repeated entries count from the first one and repeated exits up to the last one.
+func (r *Record) tally(events []event, end time.Time, assumeExitAfter time.Duration) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+	r.Events = len(events)
+
+	var in, out time.Time
+	started := false
+	closeStay := func() {
+		if started && !out.IsZero() {
+			r.Presence += out.Sub(in)
+		}
+	}
+	for _, e := range events {
+		switch e.direction {
+		case DirectionIn:
+			if r.FirstIn.IsZero() {
+				r.FirstIn = e.at
+			}
+			if started && !out.IsZero() {
+				closeStay()
+				started = false
+			}
+			if !started {
+				in, out, started = e.at, time.Time{}, true
+			}
+		case DirectionOut:
+			r.LastOut = e.at
+			if !started {
+				r.MissingEntry = true
+				continue
+			}
+			out = e.at
+		}
+	}
+
+	if started && out.IsZero() {
+		r.MissingExit = true
+		if assumeExitAfter > 0 {
+			out = in.Add(assumeExitAfter)
+			if out.After(end) {
+				out = end
+			}
+		}
+	}
+	closeStay()
+}
+
+// Generate fetches the users and the access history between from and to of an organization,
+// and builds an attendance report from them.
+func Generate(
+	ctx context.Context,
+	client *akerun.Client,
+	token *oauth2.Token,
+	organizationId string,
+	from, to time.Time,
+	opts Options,
+) (*Report, error) {
+	users, err := listing.Users(ctx, client, token, organizationId)
+	if err != nil {
+		return nil, err
+	}
+	accesses, err := listAccesses(ctx, client, token, organizationId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return Build(accesses, users, opts), nil
+}
+
+// listAccesses returns the access history of an organization between from and to.
+func listAccesses(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string, from, to time.Time) ([]akerun.Access, error) {
+	return listing.Accesses(ctx, client, token, organizationId, akerun.AccessesParameter{
+		DateTimeAfter:  from.UTC().Format(time.RFC3339),
+		DateTimeBefore: to.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,160 @@
+package attendance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+var jst = time.FixedZone("JST", 9*60*60)
+
+// access returns an access event of a user at a time in JST.
+func access(userId, userName, deviceType, at string) akerun.Access {
+	t, err := time.ParseInLocation("2006-01-02 15:04", at, jst)
+	if err != nil {
+		panic(err)
+	}
+	a := akerun.Access{DeviceType: deviceType, AccessedAt: t.UTC().Format(time.RFC3339)}
+	a.User.ID = userId
+	a.User.Name = userName
+	return a
+}
+
+func TestBuild(t *testing.T) {
+	accesses := []akerun.Access{
+		access("user1", "Alice", "nfc_outside", "2024-01-01 09:00"),
+		access("user1", "Alice", "nfc_outside", "2024-01-01 09:01"),
+		access("user1", "Alice", "nfc_inside", "2024-01-01 12:00"),
+		access("user1", "Alice", "nfc_outside", "2024-01-01 13:00"),
+		access("user1", "Alice", "nfc_inside", "2024-01-01 18:05"),
+		access("user1", "Alice", "nfc_inside", "2024-01-01 18:00"),
+		access("user2", "Guest", "nfc_outside", "2024-01-01 10:00"),
+		access("user1", "Alice", "app", "2024-01-01 11:00"),
+		access("", "", "nfc_outside", "2024-01-01 11:00"),
+	}
+	users := []akerun.User{{ID: "user1", Name: "Alice Smith", Code: "E001"}}
+
+	report := Build(accesses, users, Options{Location: jst})
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, []Record{
+		{
+			Date:     "2024-01-01",
+			UserID:   "user1",
+			UserName: "Alice Smith",
+			UserCode: "E001",
+			FirstIn:  time.Date(2024, 1, 1, 9, 0, 0, 0, jst),
+			LastOut:  time.Date(2024, 1, 1, 18, 5, 0, 0, jst),
+			Presence: 8*time.Hour + 5*time.Minute,
+			Events:   6,
+		},
+		{
+			Date:        "2024-01-01",
+			UserID:      "user2",
+			UserName:    "Guest",
+			FirstIn:     time.Date(2024, 1, 1, 10, 0, 0, 0, jst),
+			MissingExit: true,
+			Events:      1,
+		},
+	}, report.Records)
+
+	// A missing exit can be assumed after a fixed time, capped at the end of the day
+	report = Build(accesses, users, Options{Location: jst, AssumeExitAfter: 8 * time.Hour})
+	assert.Equal(t, 8*time.Hour, report.Records[1].Presence)
+	report = Build(accesses, users, Options{Location: jst, AssumeExitAfter: 20 * time.Hour})
+	assert.Equal(t, 14*time.Hour, report.Records[1].Presence)
+}
+
+func TestBuild_DayBoundary(t *testing.T) {
+	accesses := []akerun.Access{
+		access("user1", "Alice", "nfc_outside", "2024-01-02 22:00"),
+		access("user1", "Alice", "nfc_inside", "2024-01-03 02:00"),
+	}
+
+	// Without a day boundary, the night shift is split into two days
+	report := Build(accesses, nil, Options{Location: jst})
+	assert.Len(t, report.Records, 2)
+	assert.True(t, report.Records[0].MissingExit)
+	assert.True(t, report.Records[1].MissingEntry)
+	assert.Equal(t, time.Duration(0), report.Records[1].Presence)
+
+	report = Build(accesses, nil, Options{Location: jst, DayBoundary: 4 * time.Hour})
+	assert.Len(t, report.Records, 1)
+	assert.Equal(t, "2024-01-02", report.Records[0].Date)
+	assert.Equal(t, 4*time.Hour, report.Records[0].Presence)
+	assert.False(t, report.Records[0].MissingExit)
+}
+
+func TestBuild_DayBoundaryDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, min int) string {
+		return time.Date(2024, 3, day, hour, min, 0, 0, newYork).UTC().Format(time.RFC3339)
+	}
+	accesses := []akerun.Access{
+		{DeviceType: "nfc_outside", AccessedAt: at(9, 22, 0)},
+		{DeviceType: "nfc_inside", AccessedAt: at(10, 3, 30)},
+		// Clocks go forward at 2 a.m. on 2024-03-10, so 4:30 is after the 4 a.m. boundary
+		{DeviceType: "nfc_outside", AccessedAt: at(10, 4, 30)},
+	}
+	for i := range accesses {
+		accesses[i].User.ID = "user1"
+	}
+
+	report := Build(accesses, nil, Options{Location: newYork, DayBoundary: 4 * time.Hour})
+	assert.Len(t, report.Records, 2)
+	assert.Equal(t, "2024-03-09", report.Records[0].Date)
+	// 22:00 EST to 3:30 EDT
+	assert.Equal(t, 4*time.Hour+30*time.Minute, report.Records[0].Presence)
+	assert.Equal(t, "2024-03-10", report.Records[1].Date)
+	assert.True(t, report.Records[1].MissingExit)
+}
+
+func TestGenerate(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/v3/organizations/org1/users":
+			body = `{"users":[{"id":"user1","name":"Alice","code":"E001"}]}`
+		case "/v3/organizations/org1/accesses":
+			assert.Equal(t, "2024-01-01T00:00:00Z", r.URL.Query().Get("datetime_after"))
+			assert.Equal(t, "2024-01-02T00:00:00Z", r.URL.Query().Get("datetime_before"))
+			body = `{"accesses":[
+				{"id":"1","action":"unlock","device_type":"nfc_outside","accessed_at":"2024-01-01T00:00:00Z","user":{"id":"user1"}},
+				{"id":"2","action":"unlock","device_type":"nfc_inside","accessed_at":"2024-01-01T09:00:00Z","user":{"id":"user1"}}
+			]}`
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	report, err := Generate(context.Background(), client, token, "org1", from, from.AddDate(0, 0, 1), Options{Location: jst})
+	assert.NoError(t, err)
+	assert.Len(t, report.Records, 1)
+	assert.Equal(t, "Alice", report.Records[0].UserName)
+	assert.Equal(t, "E001", report.Records[0].UserCode)
+	assert.Equal(t, 9*time.Hour, report.Records[0].Presence)
+}
@@ -0,0 +1,88 @@
+package attendance
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader is the header row of WriteCSV.
+var csvHeader = []string{
+	"date", "user_id", "user_name", "user_code", "first_in", "last_out",
+	"presence_seconds", "missing_exit", "missing_entry", "events",
+}
+
+// jsonRecord represents a record as written by WriteJSON.
+type jsonRecord struct {
+	Date            string `json:"date"`
+	UserID          string `json:"user_id"`
+	UserName        string `json:"user_name"`
+	UserCode        string `json:"user_code,omitempty"`
+	FirstIn         string `json:"first_in,omitempty"`
+	LastOut         string `json:"last_out,omitempty"`
+	PresenceSeconds int64  `json:"presence_seconds"`
+	MissingExit     bool   `json:"missing_exit"`
+	MissingEntry    bool   `json:"missing_entry"`
+	Events          int    `json:"events"`
+}
+
+// WriteCSV writes the records as CSV with a header row. Times are in RFC 3339 in the time zone of the report,
+// and empty if there was no entry or exit. The presence is in whole seconds, as in WriteJSON.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, rec := range r.Records {
+		err := cw.Write([]string{
+			rec.Date,
+			rec.UserID,
+			rec.UserName,
+			rec.UserCode,
+			formatTime(rec.FirstIn),
+			formatTime(rec.LastOut),
+			strconv.FormatInt(int64(rec.Presence/time.Second), 10),
+			strconv.FormatBool(rec.MissingExit),
+			strconv.FormatBool(rec.MissingEntry),
+			strconv.Itoa(rec.Events),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the records as a JSON array. Times are in RFC 3339 in the time zone of the report,
+// and omitted if there was no entry or exit. The presence is in whole seconds, as in WriteCSV.
+func (r *Report) WriteJSON(w io.Writer) error {
+	records := make([]jsonRecord, 0, len(r.Records))
+	for _, rec := range r.Records {
+		records = append(records, jsonRecord{
+			Date:            rec.Date,
+			UserID:          rec.UserID,
+			UserName:        rec.UserName,
+			UserCode:        rec.UserCode,
+			FirstIn:         formatTime(rec.FirstIn),
+			LastOut:         formatTime(rec.LastOut),
+			PresenceSeconds: int64(rec.Presence / time.Second),
+			MissingExit:     rec.MissingExit,
+			MissingEntry:    rec.MissingEntry,
+			Events:          rec.Events,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// formatTime formats t in RFC 3339, or returns an empty string if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
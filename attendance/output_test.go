@@ -0,0 +1,54 @@
+package attendance
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_Write(t *testing.T) {
+	report := &Report{
+		Location: jst,
+		Records: []Record{
+			{
+				Date:     "2024-01-01",
+				UserID:   "user1",
+				UserName: "Alice, Smith",
+				UserCode: "E001",
+				FirstIn:  time.Date(2024, 1, 1, 9, 0, 0, 0, jst),
+				LastOut:  time.Date(2024, 1, 1, 18, 5, 0, 0, jst),
+				Presence: 8*time.Hour + 5*time.Minute,
+				Events:   4,
+			},
+			{
+				Date:        "2024-01-01",
+				UserID:      "user2",
+				UserName:    "Guest",
+				FirstIn:     time.Date(2024, 1, 1, 10, 0, 0, 0, jst),
+				MissingExit: true,
+				Events:      1,
+			},
+		},
+	}
+
+	var csv strings.Builder
+	assert.NoError(t, report.WriteCSV(&csv))
+	assert.Equal(t, `date,user_id,user_name,user_code,first_in,last_out,presence_seconds,missing_exit,missing_entry,events
+2024-01-01,user1,"Alice, Smith",E001,2024-01-01T09:00:00+09:00,2024-01-01T18:05:00+09:00,29100,false,false,4
+2024-01-01,user2,Guest,,2024-01-01T10:00:00+09:00,,0,true,false,1
+`, csv.String())
+
+	var json strings.Builder
+	assert.NoError(t, report.WriteJSON(&json))
+	assert.JSONEq(t, `[
+		{"date":"2024-01-01","user_id":"user1","user_name":"Alice, Smith","user_code":"E001","first_in":"2024-01-01T09:00:00+09:00","last_out":"2024-01-01T18:05:00+09:00","presence_seconds":29100,"missing_exit":false,"missing_entry":false,"events":4},
+		{"date":"2024-01-01","user_id":"user2","user_name":"Guest","first_in":"2024-01-01T10:00:00+09:00","presence_seconds":0,"missing_exit":true,"missing_entry":false,"events":1}
+	]`, json.String())
+
+	// An empty report is an empty array
+	json.Reset()
+	assert.NoError(t, (&Report{}).WriteJSON(&json))
+	assert.Equal(t, "[]\n", json.String())
+}
@@ -0,0 +1,119 @@
+// Package backup snapshots the configuration of an Akerun organization into an archive file
+// and restores it into the same or a different organization.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/Hayao0819/go-akerun/internal/jsonfile"
+	"github.com/Hayao0819/go-akerun/internal/listing"
+	"golang.org/x/oauth2"
+)
+
+// Version is the archive format version written by this package.
+const Version = 1
+
+// ErrUnsupportedVersion is returned when an archive was written by a newer version of this package.
+var ErrUnsupportedVersion = errors.New("backup: unsupported archive version")
+
+// Archive represents the configuration of an organization at a point in time.
+// NFC cards are part of Users.
+type Archive struct {
+	Version        int                          `json:"version"`
+	OrganizationID string                       `json:"organization_id"`
+	CreatedAt      time.Time                    `json:"created_at"`
+	Users          []akerun.User                `json:"users"`
+	Akeruns        []akerun.Akerun              `json:"akeruns"`
+	AkerunGroups   []akerun.AkerunGroupDetailed `json:"akerun_groups"`
+	Keys           []akerun.Key                 `json:"keys"`
+	// KeyURLs lists the IDs of the keys that had a key URL. The key URLs themselves grant access
+	// and are left out of the archive; restored keys get new ones.
+	KeyURLs []string `json:"key_urls,omitempty"`
+}
+
+// Create snapshots the users, Akeruns, Akerun groups with their memberships and keys of an organization.
+func Create(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string) (*Archive, error) {
+	archive := &Archive{
+		Version:        Version,
+		OrganizationID: organizationId,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	var err error
+	if archive.Users, err = listing.Users(ctx, client, token, organizationId); err != nil {
+		return nil, err
+	}
+	if archive.Akeruns, err = listing.Akeruns(ctx, client, token, organizationId); err != nil {
+		return nil, err
+	}
+	if archive.AkerunGroups, err = listing.AkerunGroups(ctx, client, token, organizationId); err != nil {
+		return nil, err
+	}
+	if archive.Keys, err = listing.Keys(ctx, client, token, organizationId); err != nil {
+		return nil, err
+	}
+	return redactKeyURLs(archive), nil
+}
+
+// redactKeyURLs returns a copy of the archive whose keys have no key URL,
+// recording in KeyURLs which of them had one.
+func redactKeyURLs(archive *Archive) *Archive {
+	redacted := *archive
+	redacted.Keys = make([]akerun.Key, len(archive.Keys))
+	redacted.KeyURLs = append([]string{}, archive.KeyURLs...)
+	for i, k := range archive.Keys {
+		if k.Keys.KeyUrl != "" {
+			redacted.KeyURLs = append(redacted.KeyURLs, k.ID)
+			k.Keys.KeyUrl = ""
+		}
+		redacted.Keys[i] = k
+	}
+	if len(redacted.KeyURLs) == 0 {
+		redacted.KeyURLs = nil
+	}
+	return &redacted
+}
+
+// Write encodes the archive to w. Key URLs are left out.
+func Write(w io.Writer, archive *Archive) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(redactKeyURLs(archive))
+}
+
+// Read decodes an archive from r.
+func Read(r io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, err
+	}
+	if archive.Version < 1 || archive.Version > Version {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, archive.Version)
+	}
+	return &archive, nil
+}
+
+// WriteFile writes the archive to the file at path atomically.
+// The file is readable by the owner only, as it holds personal data.
+func WriteFile(path string, archive *Archive) error {
+	return jsonfile.WriteFunc(path, func(w io.Writer) error {
+		return Write(w, archive)
+	})
+}
+
+// ReadFile reads an archive from the file at path.
+func ReadFile(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
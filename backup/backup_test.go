@@ -0,0 +1,334 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// sourceServer returns a server that serves the organization org1.
+func sourceServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/v3/organizations/org1/users":
+			body = `{"users":[
+				{"id":"U1","name":"Alice","mail":"alice@example.com","authority":"general","code":"E001","nfcs":[{"id":"NFC1","name":"Card"}]},
+				{"id":"U2","name":"Bob","mail":"bob@example.com","authority":"general","code":"E002"}
+			]}`
+		case "/v3/organizations/org1/akeruns":
+			body = `{"akeruns":[{"id":"A1","name":"Front","autolock":true},{"id":"A2","name":"Back"}]}`
+		case "/v3/organizations/org1/akerun_groups":
+			body = `{"akerun_groups":[{"id":"G1","name":"Doors"}]}`
+		case "/v3/organizations/org1/akerun_groups/G1":
+			body = `{"akerun_group":{"id":"G1","name":"Doors","memo":"All doors","akeruns":[{"id":"A1","name":"Front"},{"id":"A2","name":"Back"}]}}`
+		case "/v3/organizations/org1/keys":
+			body = `{"keys":[
+				{"id":"K1","role":"user","schedule_type":"permanent","akerun":{"id":"A1","name":"Front"},"user":{"id":"U1","name":"Alice"}},
+				{"id":"K2","role":"user","schedule_type":"recurring","recurring_schedule":{"days_of_week":[1,2],"start_time":"09:00","end_time":"18:00"},"keys":{"key_url":"https://example.com/k","password_protected":true},"akerun":{"id":"A1","name":"Front"},"user":{"id":"U2","name":"Bob"}},
+				{"id":"K3","role":"user","schedule_type":"permanent","akerun":{"id":"A2","name":"Back"},"user":{"id":"U2","name":"Bob"}}
+			]}`
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+}
+
+func TestCreate(t *testing.T) {
+	ts := sourceServer(t)
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	archive, err := Create(context.Background(), client, token, "org1")
+	assert.NoError(t, err)
+	assert.Equal(t, Version, archive.Version)
+	assert.Len(t, archive.Users, 2)
+	assert.Equal(t, "NFC1", archive.Users[0].Nfcs[0].ID)
+	assert.Len(t, archive.Akeruns, 2)
+	assert.Len(t, archive.AkerunGroups[0].Akeruns, 2)
+	assert.Len(t, archive.Keys, 3)
+	// Key URLs grant access, so only whether a key had one is kept
+	assert.Empty(t, archive.Keys[1].Keys.KeyUrl)
+	assert.True(t, archive.Keys[1].Keys.PasswordProtected)
+	assert.Equal(t, []string{"K2"}, archive.KeyURLs)
+
+	// The archive survives a round trip through a file
+	path := filepath.Join(t.TempDir(), "backup.json")
+	assert.NoError(t, WriteFile(path, archive))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	byt, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(byt), "https://example.com/k")
+
+	loaded, err := ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, archive.CreatedAt.Unix(), loaded.CreatedAt.Unix())
+	assert.Equal(t, archive.Keys, loaded.Keys)
+	assert.Equal(t, archive.Users, loaded.Users)
+
+	_, err = Read(strings.NewReader(`{"version":2}`))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+// targetServer returns a server that serves the organization org2 and records the mutating requests.
+func targetServer(t *testing.T, requests *[]string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			assert.NoError(t, r.ParseForm())
+			mu.Lock()
+			*requests = append(*requests, r.Method+" "+r.URL.Path+" "+r.PostForm.Encode())
+			mu.Unlock()
+		}
+
+		var body string
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v3/organizations/org2/users":
+			body = `{"users":[{"id":"X1","name":"Alice","mail":"alice@example.com","authority":"general","code":"E001","nfcs":[{"id":"NFC1"}]}]}`
+		case "GET /v3/organizations/org2/akeruns":
+			body = `{"akeruns":[{"id":"B1","name":"Front"}]}`
+		case "GET /v3/organizations/org2/akerun_groups":
+			body = `{"akerun_groups":[]}`
+		case "GET /v3/organizations/org2/keys":
+			body = `{"keys":[{"id":"L1","role":"user","schedule_type":"permanent","akerun":{"id":"B1"},"user":{"id":"X1"}}]}`
+		case "POST /v3/organizations/org2/users":
+			body = `{"user":{"id":"X2"}}`
+		case "POST /v3/organizations/org2/akerun_groups":
+			body = `{"akerun_group":{"id":"H1"}}`
+		case "POST /v3/organizations/org2/akerun_groups/H1/akeruns":
+			body = `{"akerun_group":{"id":"H1"}}`
+		case "POST /v3/organizations/org2/keys":
+			body = `{"key":{"id":"L2"}}`
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+}
+
+func TestRestore(t *testing.T) {
+	source := sourceServer(t)
+	defer source.Close()
+	var requests []string
+	target := targetServer(t, &requests)
+	defer target.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	token := &oauth2.Token{AccessToken: "test_token"}
+	ctx := context.Background()
+
+	os.Setenv("AKERUN_API_URL", source.URL)
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	archive, err := Create(ctx, client, token, "org1")
+	assert.NoError(t, err)
+
+	os.Setenv("AKERUN_API_URL", target.URL)
+	client = akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	opts := Options{DryRun: true, AkerunIDs: map[string]string{"A1": "B1"}}
+
+	// A dry run changes nothing and reports the plan
+	report, err := Restore(ctx, client, token, archive, "org2", opts)
+	assert.NoError(t, err)
+	assert.Empty(t, requests)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, map[string]string{"U1": "X1", "U2": "dry-run:U2"}, report.IDs[ResourceUser])
+	assert.Equal(t, map[string]string{"A1": "B1"}, report.IDs[ResourceAkerun])
+	assert.Equal(t, map[string]string{"G1": "dry-run:G1"}, report.IDs[ResourceAkerunGroup])
+	assert.Equal(t, map[string]string{"K1": "L1", "K2": "dry-run:K2"}, report.IDs[ResourceKey])
+
+	var out strings.Builder
+	assert.NoError(t, report.Format(&out))
+	assert.Contains(t, out.String(), "settings differ, but cannot be changed through the API")
+	assert.Contains(t, out.String(), "key URL passwords cannot be restored")
+
+	// The actual restore sends the planned requests
+	opts.DryRun = false
+	report, err = Restore(ctx, client, token, archive, "org2", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"POST /v3/organizations/org2/users user_authority=general&user_code=E002&user_mail=bob%40example.com&user_name=Bob",
+		"POST /v3/organizations/org2/akerun_groups memo=All+doors&name=Doors",
+		"POST /v3/organizations/org2/akerun_groups/H1/akeruns akerun_ids%5B%5D=B1",
+		"POST /v3/organizations/org2/keys akerun_id=B1&recurring_schedule%5Bdays_of_week%5D=1&recurring_schedule%5Bdays_of_week%5D=2&recurring_schedule%5Bend_time%5D=18%3A00&recurring_schedule%5Bstart_time%5D=09%3A00&role=user&schedule_type=recurring&user_id=X2",
+	}, requests)
+
+	assert.Equal(t, 4, report.Count(ActionCreate))
+	assert.Equal(t, 0, report.Count(ActionUpdate))
+	// User U1, its NFC card and key K1 already exist
+	assert.Equal(t, 3, report.Count(ActionUnchanged))
+	// Akerun A1 has other settings, and A2, its group membership and key K3 are not in the target
+	assert.Equal(t, 4, report.Count(ActionSkip))
+}
+
+func TestRestore_Twice(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		users    []string
+		requests []string
+	)
+	// Create a test server whose users include the ones created by earlier requests
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var body string
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v3/organizations/org2/users":
+			body = `{"users":[` + strings.Join(users, ",") + `]}`
+		case "GET /v3/organizations/org2/akeruns":
+			body = `{"akeruns":[]}`
+		case "GET /v3/organizations/org2/akerun_groups":
+			body = `{"akerun_groups":[]}`
+		case "GET /v3/organizations/org2/keys":
+			body = `{"keys":[]}`
+		case "POST /v3/organizations/org2/users":
+			assert.NoError(t, r.ParseForm())
+			requests = append(requests, r.PostForm.Get("user_name"))
+			id := fmt.Sprintf("X%d", len(users)+1)
+			users = append(users, `{"id":"`+id+`","name":"`+r.PostForm.Get("user_name")+`"}`)
+			body = `{"user":{"id":"` + id + `"}}`
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	archive := &Archive{Version: Version, Users: []akerun.User{
+		{ID: "U1", Name: "Carol"},
+		{ID: "U2", Name: "Guest"},
+		{ID: "U3", Name: "Guest"},
+	}}
+
+	// Users without a code or mail are matched by name, and namesakes are skipped
+	for i := 0; i < 2; i++ {
+		report, err := Restore(context.Background(), client, token, archive, "org2", Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"U1": "X1"}, report.IDs[ResourceUser])
+		assert.Equal(t, 2, report.Count(ActionSkip))
+	}
+	assert.Equal(t, []string{"Carol"}, requests)
+}
+
+func TestRestore_KeysOfSameHolder(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		keys     = []string{`{"id":"L1","role":"user","schedule_type":"recurring","recurring_schedule":{"days_of_week":[0,6],"start_time":"10:00","end_time":"16:00"},"akerun":{"id":"B1"},"user":{"id":"X1"}}`}
+		requests []string
+	)
+	// Create a test server whose keys include the ones created by earlier requests
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var body string
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v3/organizations/org2/users":
+			body = `{"users":[{"id":"X1","name":"Alice","code":"E001"}]}`
+		case "GET /v3/organizations/org2/akeruns":
+			body = `{"akeruns":[{"id":"B1","name":"Front"}]}`
+		case "GET /v3/organizations/org2/akerun_groups":
+			body = `{"akerun_groups":[]}`
+		case "GET /v3/organizations/org2/keys":
+			body = `{"keys":[` + strings.Join(keys, ",") + `]}`
+		case "POST /v3/organizations/org2/keys":
+			assert.NoError(t, r.ParseForm())
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+r.PostForm.Encode())
+			id := fmt.Sprintf("L%d", len(keys)+1)
+			keys = append(keys, `{"id":"`+id+`","role":"`+r.PostForm.Get("role")+`","schedule_type":"recurring","recurring_schedule":{"days_of_week":[`+
+				strings.Join(r.PostForm["recurring_schedule[days_of_week]"], ",")+`],"start_time":"`+r.PostForm.Get("recurring_schedule[start_time]")+
+				`","end_time":"`+r.PostForm.Get("recurring_schedule[end_time]")+`"},"akerun":{"id":"B1"},"user":{"id":"X1"}}`)
+			body = `{"key":{"id":"` + id + `"}}`
+		default:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	archive := &Archive{
+		Version: Version,
+		Users:   []akerun.User{{ID: "U1", Name: "Alice", Code: "E001"}},
+		Akeruns: []akerun.Akerun{{ID: "A1", Name: "Front"}},
+	}
+	// A weekday and a weekend key of the same user for the same Akerun
+	for _, k := range []struct {
+		id, start, end string
+		days           []uint32
+	}{
+		{"K1", "09:00", "18:00", []uint32{1, 2, 3, 4, 5}},
+		{"K2", "10:00", "16:00", []uint32{0, 6}},
+	} {
+		key := akerun.Key{ID: k.id, Role: "user", ScheduleType: akerun.ScheduleTypeRecurring}
+		key.RecurringSchedule.DaysOfWeek = k.days
+		key.RecurringSchedule.StartTime = k.start
+		key.RecurringSchedule.EndTime = k.end
+		key.Akerun.ID, key.Akerun.Name = "A1", "Front"
+		key.User.ID, key.User.Name = "U1", "Alice"
+		archive.Keys = append(archive.Keys, key)
+	}
+
+	opts := Options{AkerunIDs: map[string]string{"A1": "B1"}}
+
+	// The existing weekend key is kept and only the weekday key is created
+	report, err := Restore(context.Background(), client, token, archive, "org2", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"K1": "L2", "K2": "L1"}, report.IDs[ResourceKey])
+	assert.Equal(t, []string{
+		"POST /v3/organizations/org2/keys akerun_id=B1&recurring_schedule%5Bdays_of_week%5D=1&recurring_schedule%5Bdays_of_week%5D=2&recurring_schedule%5Bdays_of_week%5D=3&recurring_schedule%5Bdays_of_week%5D=4&recurring_schedule%5Bdays_of_week%5D=5&recurring_schedule%5Bend_time%5D=18%3A00&recurring_schedule%5Bstart_time%5D=09%3A00&role=user&schedule_type=recurring&user_id=X1",
+	}, requests)
+
+	// Restoring again changes nothing
+	requests = nil
+	report, err = Restore(context.Background(), client, token, archive, "org2", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"K1": "L2", "K2": "L1"}, report.IDs[ResourceKey])
+	assert.Empty(t, requests)
+	assert.Equal(t, 0, report.Count(ActionCreate)+report.Count(ActionUpdate))
+}
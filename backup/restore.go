@@ -0,0 +1,557 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/Hayao0819/go-akerun/internal/listing"
+	"golang.org/x/oauth2"
+)
+
+// ResourceType represents the kind of a restored resource.
+type ResourceType string
+
+// Resource types
+const (
+	ResourceUser             ResourceType = "user"
+	ResourceNFC              ResourceType = "nfc"
+	ResourceAkerun           ResourceType = "akerun"
+	ResourceAkerunGroup      ResourceType = "akerun_group"
+	ResourceAkerunMembership ResourceType = "akerun_group_membership"
+	ResourceKey              ResourceType = "key"
+)
+
+// ActionType represents what Restore does with a resource.
+type ActionType string
+
+// Action types
+const (
+	ActionCreate    ActionType = "create"
+	ActionUpdate    ActionType = "update"
+	ActionUnchanged ActionType = "unchanged"
+	ActionSkip      ActionType = "skip"
+)
+
+// dryRunPrefix is prepended to the source ID to form the target ID of resources created in dry-run mode.
+const dryRunPrefix = akerun.DryRunID + ":"
+
+// Options represents the options for Restore.
+type Options struct {
+	// DryRun reports what Restore would do without changing the target organization.
+	DryRun bool
+	// AkerunIDs maps Akerun IDs in the archive to Akerun IDs in the target organization.
+	// Akeruns that are not mapped keep their ID if the target organization has them, and are skipped otherwise.
+	AkerunIDs map[string]string
+}
+
+// Action represents what Restore did, or would do in dry-run mode, with a resource.
+type Action struct {
+	Type     ActionType
+	Resource ResourceType
+	// SourceID is the ID in the archive and TargetID the ID in the target organization.
+	// Created resources have a TargetID starting with "dry-run:" in dry-run mode.
+	SourceID string
+	TargetID string
+	Name     string
+	// Reason explains skipped resources and limitations of the restore.
+	Reason string
+	// Err is the error of the API call, if it failed.
+	Err error
+}
+
+// Report represents the result of Restore.
+type Report struct {
+	DryRun  bool
+	Actions []Action
+	// IDs maps the IDs in the archive to the IDs in the target organization by resource type.
+	IDs map[ResourceType]map[string]string
+}
+
+// Count returns the number of actions of type t.
+func (r *Report) Count(t ActionType) int {
+	n := 0
+	for _, a := range r.Actions {
+		if a.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+// Format writes the report as a table to w.
+func (r *Report) Format(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tRESOURCE\tSOURCE\tTARGET\tNAME\tNOTE")
+	for _, a := range r.Actions {
+		note := a.Reason
+		if a.Err != nil {
+			note = "error: " + a.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Type, a.Resource, a.SourceID, a.TargetID, a.Name, note)
+	}
+	return tw.Flush()
+}
+
+// restorer holds the state of a single Restore call.
+type restorer struct {
+	client         *akerun.Client
+	token          *oauth2.Token
+	organizationId string
+	opts           Options
+	report         *Report
+	errs           []error
+}
+
+// Restore replays the archive into the organization with the create and update methods of client.
+// Resources are matched to existing ones, so that restoring twice does not create duplicates:
+// users by code, then mail, Akerun groups by name, and keys by user and Akerun.
+// Resources that depend on a resource that could not be restored are skipped.
+//
+// The API has no methods to register NFC cards or change Akerun settings,
+// so differences in them are reported as skipped rather than restored.
+// Restore continues after failed API calls and returns them joined together with the report.
+func Restore(
+	ctx context.Context,
+	client *akerun.Client,
+	token *oauth2.Token,
+	archive *Archive,
+	organizationId string,
+	opts Options,
+) (*Report, error) {
+	r := &restorer{
+		client:         client,
+		token:          token,
+		organizationId: organizationId,
+		opts:           opts,
+		report: &Report{
+			DryRun: opts.DryRun,
+			IDs: map[ResourceType]map[string]string{
+				ResourceUser:        {},
+				ResourceAkerun:      {},
+				ResourceAkerunGroup: {},
+				ResourceKey:         {},
+			},
+		},
+	}
+
+	if err := r.restoreAkeruns(ctx, archive.Akeruns); err != nil {
+		return nil, err
+	}
+	if err := r.restoreUsers(ctx, archive.Users); err != nil {
+		return nil, err
+	}
+	if err := r.restoreAkerunGroups(ctx, archive.AkerunGroups); err != nil {
+		return nil, err
+	}
+	if err := r.restoreKeys(ctx, archive.Keys, archive.KeyURLs); err != nil {
+		return nil, err
+	}
+	return r.report, errors.Join(r.errs...)
+}
+
+// record adds an action to the report and maps its IDs.
+func (r *restorer) record(a Action) {
+	if a.Err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s %s %s: %w", a.Type, a.Resource, a.SourceID, a.Err))
+	}
+	if ids, ok := r.report.IDs[a.Resource]; ok && a.TargetID != "" && a.Err == nil {
+		ids[a.SourceID] = a.TargetID
+	}
+	r.report.Actions = append(r.report.Actions, a)
+}
+
+// created returns the target ID of a created resource, which is a placeholder in dry-run mode.
+func (r *restorer) created(sourceId string, create func() (string, error)) (string, error) {
+	if r.opts.DryRun {
+		return dryRunPrefix + sourceId, nil
+	}
+	return create()
+}
+
+// updated calls update unless in dry-run mode.
+func (r *restorer) updated(update func() error) error {
+	if r.opts.DryRun {
+		return nil
+	}
+	return update()
+}
+
+// akerunSettings represents the settings of an Akerun that are part of the backup.
+type akerunSettings struct {
+	Name                string
+	OpenDoorAlert       bool
+	OpenDoorAlertSecond int
+	PushButton          bool
+	NormalSoundVolume   int
+	AlertSoundVolume    int
+	Autolock            bool
+	AutolockOffSchedule interface{}
+}
+
+// settingsOf returns the settings of an Akerun.
+func settingsOf(a akerun.Akerun) akerunSettings {
+	return akerunSettings{
+		Name:                a.Name,
+		OpenDoorAlert:       a.OpenDoorAlert,
+		OpenDoorAlertSecond: a.OpenDoorAlertSecond,
+		PushButton:          a.PushButton,
+		NormalSoundVolume:   a.NormalSoundVolume,
+		AlertSoundVolume:    a.AlertSoundVolume,
+		Autolock:            a.Autolock,
+		AutolockOffSchedule: a.AutolockOffSchedule,
+	}
+}
+
+// restoreAkeruns maps the Akeruns of the archive to the Akeruns of the target organization and reports setting differences.
+func (r *restorer) restoreAkeruns(ctx context.Context, akeruns []akerun.Akerun) error {
+	existing, err := listing.Akeruns(ctx, r.client, r.token, r.organizationId)
+	if err != nil {
+		return err
+	}
+	byID := map[string]akerun.Akerun{}
+	for _, a := range existing {
+		byID[a.ID] = a
+	}
+
+	for _, a := range akeruns {
+		action := Action{Resource: ResourceAkerun, SourceID: a.ID, Name: a.Name}
+		targetId := a.ID
+		if mapped, ok := r.opts.AkerunIDs[a.ID]; ok {
+			targetId = mapped
+		}
+		target, ok := byID[targetId]
+		switch {
+		case !ok:
+			action.Type = ActionSkip
+			action.Reason = "Akerun not found in the target organization"
+		case reflect.DeepEqual(settingsOf(a), settingsOf(target)):
+			action.Type = ActionUnchanged
+			action.TargetID = targetId
+		default:
+			action.Type = ActionSkip
+			action.TargetID = targetId
+			action.Reason = "settings differ, but cannot be changed through the API"
+		}
+		if ok {
+			// The Akerun is usable by groups and keys even if its settings differ.
+			r.report.IDs[ResourceAkerun][a.ID] = targetId
+		}
+		r.record(action)
+	}
+	return nil
+}
+
+// restoreUsers creates or updates the users of the archive.
+func (r *restorer) restoreUsers(ctx context.Context, users []akerun.User) error {
+	existing, err := listing.Users(ctx, r.client, r.token, r.organizationId)
+	if err != nil {
+		return err
+	}
+	byCode := map[string]akerun.User{}
+	byMail := map[string]akerun.User{}
+	// Users without a code or mail can only be told apart by name.
+	byName := map[string][]akerun.User{}
+	for _, u := range existing {
+		if u.Code != "" {
+			byCode[u.Code] = u
+		}
+		if u.Mail != "" {
+			byMail[u.Mail] = u
+		}
+		if u.Code == "" && u.Mail == "" {
+			byName[u.Name] = append(byName[u.Name], u)
+		}
+	}
+	namesakes := map[string]int{}
+	for _, u := range users {
+		if u.Code == "" && u.Mail == "" {
+			namesakes[u.Name]++
+		}
+	}
+
+	for _, u := range users {
+		action := Action{Resource: ResourceUser, SourceID: u.ID, Name: u.Name}
+		var (
+			target    akerun.User
+			ok        bool
+			ambiguous bool
+		)
+		if u.Code == "" && u.Mail == "" {
+			matches := byName[u.Name]
+			ambiguous = namesakes[u.Name] > 1 || len(matches) > 1
+			if len(matches) == 1 {
+				target, ok = matches[0], true
+			}
+		} else if target, ok = byCode[u.Code]; !ok {
+			target, ok = byMail[u.Mail]
+		}
+
+		switch {
+		case ambiguous:
+			action.Type = ActionSkip
+			action.Reason = "the user has no code or mail and shares the name with other users; restore it manually"
+		case !ok:
+			action.Type = ActionCreate
+			action.TargetID, action.Err = r.created(u.ID, func() (string, error) {
+				created, err := r.client.RegisterUser(ctx, r.token, r.organizationId, u.Name, akerun.RegisterUserParameter{
+					UserMail:      u.Mail,
+					UserAuthority: u.Authority,
+					UserCode:      u.Code,
+				})
+				if err != nil {
+					return "", err
+				}
+				return created.ID, nil
+			})
+		case target.Name == u.Name && target.Mail == u.Mail && target.Authority == u.Authority && target.Code == u.Code:
+			action.Type = ActionUnchanged
+			action.TargetID = target.ID
+		default:
+			action.Type = ActionUpdate
+			action.TargetID = target.ID
+			action.Err = r.updated(func() error {
+				_, err := r.client.UpdateUser(ctx, r.token, r.organizationId, target.ID, akerun.UpdateUserParameter{
+					UserName:      u.Name,
+					UserMail:      u.Mail,
+					UserAuthority: u.Authority,
+					UserCode:      u.Code,
+				})
+				return err
+			})
+		}
+		r.record(action)
+		if action.Type != ActionSkip {
+			r.restoreNFCs(u, target, action)
+		}
+	}
+	return nil
+}
+
+// restoreNFCs reports the NFC cards of a user, which cannot be registered through the API.
+func (r *restorer) restoreNFCs(u, target akerun.User, userAction Action) {
+	registered := map[string]bool{}
+	for _, nfc := range target.Nfcs {
+		registered[nfc.ID] = true
+	}
+	for _, nfc := range u.Nfcs {
+		action := Action{Resource: ResourceNFC, SourceID: nfc.ID, Name: nfc.Name}
+		if registered[nfc.ID] && userAction.Err == nil {
+			action.Type = ActionUnchanged
+			action.TargetID = nfc.ID
+		} else {
+			action.Type = ActionSkip
+			action.Reason = fmt.Sprintf("NFC cards cannot be registered through the API; register it to user %s manually", u.Name)
+		}
+		r.record(action)
+	}
+}
+
+// restoreAkerunGroups creates or updates the Akerun groups of the archive and adds their Akeruns.
+func (r *restorer) restoreAkerunGroups(ctx context.Context, groups []akerun.AkerunGroupDetailed) error {
+	existing, err := listing.AkerunGroups(ctx, r.client, r.token, r.organizationId)
+	if err != nil {
+		return err
+	}
+	byName := map[string]akerun.AkerunGroupDetailed{}
+	for _, g := range existing {
+		byName[g.Name] = g
+	}
+
+	for _, g := range groups {
+		action := Action{Resource: ResourceAkerunGroup, SourceID: g.ID, Name: g.Name}
+		target, ok := byName[g.Name]
+		switch {
+		case !ok:
+			action.Type = ActionCreate
+			action.TargetID, action.Err = r.created(g.ID, func() (string, error) {
+				created, err := r.client.CreateAkerunGroup(ctx, r.token, r.organizationId, akerun.AkerunGroupCreateParameter{Name: g.Name, Memo: g.Memo})
+				if err != nil {
+					return "", err
+				}
+				return created.ID, nil
+			})
+		case target.Memo == g.Memo:
+			action.Type = ActionUnchanged
+			action.TargetID = target.ID
+		default:
+			action.Type = ActionUpdate
+			action.TargetID = target.ID
+			action.Err = r.updated(func() error {
+				_, err := r.client.UpdateAkerunGroup(ctx, r.token, r.organizationId, target.ID, akerun.AkerunGroupUpdateParameter{Name: g.Name, Memo: g.Memo})
+				return err
+			})
+		}
+		r.record(action)
+		if action.Err == nil {
+			r.restoreMemberships(ctx, g, target, action.TargetID)
+		}
+	}
+	return nil
+}
+
+// restoreMemberships adds the Akeruns of a group in the archive that the target group lacks.
+func (r *restorer) restoreMemberships(ctx context.Context, g, target akerun.AkerunGroupDetailed, targetGroupId string) {
+	members := map[string]bool{}
+	for _, a := range target.Akeruns {
+		members[a.ID] = true
+	}
+
+	var add []string
+	var actions []Action
+	for _, a := range g.Akeruns {
+		action := Action{Resource: ResourceAkerunMembership, SourceID: g.ID + "/" + a.ID, Name: g.Name + "/" + a.Name}
+		akerunId, ok := r.report.IDs[ResourceAkerun][a.ID]
+		switch {
+		case !ok:
+			action.Type = ActionSkip
+			action.Reason = "Akerun not found in the target organization"
+		case members[akerunId]:
+			action.Type = ActionUnchanged
+			action.TargetID = targetGroupId + "/" + akerunId
+		default:
+			action.Type = ActionCreate
+			action.TargetID = targetGroupId + "/" + akerunId
+			add = append(add, akerunId)
+		}
+		actions = append(actions, action)
+	}
+
+	var err error
+	if len(add) > 0 {
+		err = r.updated(func() error {
+			return r.client.AddAkerunToGroup(ctx, r.token, r.organizationId, targetGroupId, add...)
+		})
+	}
+	for _, action := range actions {
+		if action.Type == ActionCreate {
+			action.Err = err
+		}
+		r.record(action)
+	}
+}
+
+// restoreKeys creates or updates the keys of the archive for the restored users and Akeruns.
+func (r *restorer) restoreKeys(ctx context.Context, keys []akerun.Key, keyURLs []string) error {
+	hasKeyURL := map[string]bool{}
+	for _, id := range keyURLs {
+		hasKeyURL[id] = true
+	}
+
+	existing, err := listing.Keys(ctx, r.client, r.token, r.organizationId)
+	if err != nil {
+		return err
+	}
+	// A user can hold several keys for one Akerun, so each existing key is matched at most once:
+	// first by holder and schedule, then the remaining ones by holder only.
+	candidates := map[[2]string][]akerun.Key{}
+	for _, k := range existing {
+		holder := [2]string{k.User.ID, k.Akerun.ID}
+		candidates[holder] = append(candidates[holder], k)
+	}
+	holders := make([][2]string, len(keys))
+	restored := make([]bool, len(keys))
+	matches := make([]*akerun.Key, len(keys))
+	for i, k := range keys {
+		userId, userOk := r.report.IDs[ResourceUser][k.User.ID]
+		akerunId, akerunOk := r.report.IDs[ResourceAkerun][k.Akerun.ID]
+		holders[i] = [2]string{userId, akerunId}
+		restored[i] = userOk && akerunOk
+		if restored[i] {
+			matches[i] = takeKey(candidates, holders[i], func(c akerun.Key) bool { return sameSchedule(k, c) })
+		}
+	}
+
+	for i, k := range keys {
+		action := Action{Resource: ResourceKey, SourceID: k.ID, Name: k.User.Name + "/" + k.Akerun.Name}
+		if !restored[i] {
+			action.Type = ActionSkip
+			action.Reason = "user or Akerun was not restored"
+			r.record(action)
+			continue
+		}
+		if matches[i] != nil {
+			action.Type = ActionUnchanged
+			action.TargetID = matches[i].ID
+			r.record(action)
+			continue
+		}
+
+		userId, akerunId := holders[i][0], holders[i][1]
+		target := takeKey(candidates, holders[i], func(akerun.Key) bool { return true })
+		if target == nil {
+			action.Type = ActionCreate
+			params := createKeyParameter(k, hasKeyURL[k.ID] || k.Keys.KeyUrl != "")
+			if k.Keys.PasswordProtected {
+				action.Reason = "key URL passwords cannot be restored; the key URL is not enabled"
+			}
+			action.TargetID, action.Err = r.created(k.ID, func() (string, error) {
+				created, err := r.client.CreateKey(ctx, r.token, r.organizationId, userId, akerunId, params)
+				if err != nil {
+					return "", err
+				}
+				return created.ID, nil
+			})
+		} else {
+			action.Type = ActionUpdate
+			action.TargetID = target.ID
+			action.Err = r.updated(func() error {
+				_, err := r.client.UpdateKey(ctx, r.token, r.organizationId, target.ID, k.ScheduleType, updateKeyParameter(k))
+				return err
+			})
+		}
+		r.record(action)
+	}
+	return nil
+}
+
+// takeKey removes the first key of holder that satisfies match from candidates and returns it, or nil if there is none.
+func takeKey(candidates map[[2]string][]akerun.Key, holder [2]string, match func(akerun.Key) bool) *akerun.Key {
+	keys := candidates[holder]
+	for i, k := range keys {
+		if match(k) {
+			candidates[holder] = append(keys[:i:i], keys[i+1:]...)
+			return &k
+		}
+	}
+	return nil
+}
+
+// sameSchedule reports whether two keys have the same role and schedule.
+func sameSchedule(a, b akerun.Key) bool {
+	return a.Role == b.Role &&
+		a.ScheduleType == b.ScheduleType &&
+		a.TemporarySchedule == b.TemporarySchedule &&
+		reflect.DeepEqual(a.RecurringSchedule, b.RecurringSchedule)
+}
+
+// createKeyParameter returns the parameters to create a key like k, with a key URL if keyURL is set.
+// Password protected key URLs are not enabled, as the password is not part of the backup.
+func createKeyParameter(k akerun.Key, keyURL bool) akerun.CreateKeyParameter {
+	params := akerun.CreateKeyParameter{
+		ScheduleType: k.ScheduleType,
+		Role:         k.Role,
+		EnableKeyUrl: keyURL && !k.Keys.PasswordProtected,
+	}
+	params.TemporarySchedule.StartDateTime = k.TemporarySchedule.StartDateTime
+	params.TemporarySchedule.EndDateTime = k.TemporarySchedule.EndDateTime
+	params.RecurringSchedule.DaysOfWeek = k.RecurringSchedule.DaysOfWeek
+	params.RecurringSchedule.StartTime = k.RecurringSchedule.StartTime
+	params.RecurringSchedule.EndTime = k.RecurringSchedule.EndTime
+	return params
+}
+
+// updateKeyParameter returns the parameters to update a key to the role and schedule of k.
+func updateKeyParameter(k akerun.Key) akerun.UpdateKeyParameter {
+	params := akerun.UpdateKeyParameter{Role: k.Role}
+	params.TemporarySchedule.StartDateTime = k.TemporarySchedule.StartDateTime
+	params.TemporarySchedule.EndDateTime = k.TemporarySchedule.EndDateTime
+	params.RecurringSchedule.DaysOfWeek = k.RecurringSchedule.DaysOfWeek
+	params.RecurringSchedule.StartTime = k.RecurringSchedule.StartTime
+	params.RecurringSchedule.EndTime = k.RecurringSchedule.EndTime
+	return params
+}
@@ -0,0 +1,180 @@
+package akerun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultBulkConcurrency is the default number of concurrent requests of the bulk operations.
+const DefaultBulkConcurrency = 4
+
+// BulkStatus represents the status of an item of a bulk operation.
+type BulkStatus string
+
+// Bulk item statuses
+const (
+	BulkPending   BulkStatus = "pending"
+	BulkSucceeded BulkStatus = "succeeded"
+	BulkFailed    BulkStatus = "failed"
+)
+
+// BulkResult represents the result of an item of a bulk operation.
+type BulkResult[T any] struct {
+	// Index is the index of the item in the input.
+	Index  int
+	Status BulkStatus
+	Value  T
+	Err    error
+}
+
+// BulkReport represents the results of a bulk operation, in the order of the input items.
+type BulkReport[T any] struct {
+	Results []BulkResult[T]
+}
+
+// Count returns the number of items with the given status.
+func (r *BulkReport[T]) Count(status BulkStatus) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Err returns the errors of the failed items joined together, or nil if no item failed.
+func (r *BulkReport[T]) Err() error {
+	var errs []error
+	for _, res := range r.Results {
+		if res.Status == BulkFailed {
+			errs = append(errs, fmt.Errorf("item %d: %w", res.Index, res.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BulkOptions represents the options of a bulk operation.
+type BulkOptions[T any] struct {
+	// Concurrency is the number of concurrent requests. If zero, DefaultBulkConcurrency is used.
+	Concurrency int
+	// Resume is the report of a previous, canceled or partially failed run with the same items.
+	// Items that succeeded in it are not sent again.
+	Resume *BulkReport[T]
+}
+
+// runBulk calls fn for every item with bounded concurrency.
+// If ctx is canceled, the items not started yet are left pending and ctx.Err() is returned along with the report.
+func runBulk[I, T any](ctx context.Context, items []I, opts BulkOptions[T], fn func(ctx context.Context, item I) (T, error)) (*BulkReport[T], error) {
+	report := &BulkReport[T]{Results: make([]BulkResult[T], len(items))}
+	if opts.Resume != nil {
+		if len(opts.Resume.Results) != len(items) {
+			return nil, fmt.Errorf("akerun: resumed report has %d items, want %d", len(opts.Resume.Results), len(items))
+		}
+		copy(report.Results, opts.Resume.Results)
+	}
+
+	for i := range items {
+		if report.Results[i].Status == BulkSucceeded {
+			continue
+		}
+		report.Results[i] = BulkResult[T]{Index: i, Status: BulkPending}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBulkConcurrency
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+				value, err := fn(ctx, items[i])
+				if err != nil {
+					report.Results[i] = BulkResult[T]{Index: i, Status: BulkFailed, Err: err}
+					continue
+				}
+				report.Results[i] = BulkResult[T]{Index: i, Status: BulkSucceeded, Value: value}
+			}
+		}()
+	}
+
+send:
+	for i := range items {
+		if report.Results[i].Status == BulkSucceeded {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			break send
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return report, ctx.Err()
+}
+
+// BulkCreateKeyItem represents a key to create with BulkCreateKeys.
+type BulkCreateKeyItem struct {
+	UserId   string
+	AkerunId string
+	Params   CreateKeyParameter
+}
+
+// BulkCreateKeys creates keys concurrently. The requests go through the client's rate limiter.
+func (c *Client) BulkCreateKeys(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	items []BulkCreateKeyItem,
+	opts BulkOptions[*Key],
+) (*BulkReport[*Key], error) {
+	return runBulk(ctx, items, opts, func(ctx context.Context, item BulkCreateKeyItem) (*Key, error) {
+		return c.CreateKey(ctx, oauth2Token, organizationId, item.UserId, item.AkerunId, item.Params)
+	})
+}
+
+// BulkDeleteKeys deletes keys concurrently. The requests go through the client's rate limiter.
+func (c *Client) BulkDeleteKeys(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	keyIds []string,
+	opts BulkOptions[string],
+) (*BulkReport[string], error) {
+	return runBulk(ctx, keyIds, opts, func(ctx context.Context, keyId string) (string, error) {
+		return keyId, c.DeleteKey(ctx, oauth2Token, organizationId, keyId)
+	})
+}
+
+// BulkUpdateUserItem represents a user to update with BulkUpdateUsers.
+type BulkUpdateUserItem struct {
+	UserId string
+	Params UpdateUserParameter
+}
+
+// BulkUpdateUsers updates users concurrently. The requests go through the client's rate limiter.
+func (c *Client) BulkUpdateUsers(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	items []BulkUpdateUserItem,
+	opts BulkOptions[*User],
+) (*BulkReport[*User], error) {
+	return runBulk(ctx, items, opts, func(ctx context.Context, item BulkUpdateUserItem) (*User, error) {
+		return c.UpdateUser(ctx, oauth2Token, organizationId, item.UserId, item.Params)
+	})
+}
@@ -0,0 +1,99 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// countingLimiter counts the requests waiting on it.
+type countingLimiter struct {
+	waits atomic.Int32
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	return nil
+}
+
+func TestClient_BulkCreateKeys(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inFlight int
+		maxSeen  int
+		fail     atomic.Bool
+	)
+	fail.Store(true)
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		userId := r.PostFormValue("user_id")
+		if userId == "user3" && fail.Load() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		_, err := w.Write([]byte(`{"key":{"id":"key-` + userId + `"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	limiter := &countingLimiter{}
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.RateLimiter = limiter
+	client := NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	var items []BulkCreateKeyItem
+	for _, userId := range []string{"user1", "user2", "user3", "user4", "user5"} {
+		items = append(items, BulkCreateKeyItem{UserId: userId, AkerunId: "A1", Params: CreateKeyParameter{ScheduleType: "permanent"}})
+	}
+
+	report, err := client.BulkCreateKeys(context.Background(), token, "org1", items, BulkOptions[*Key]{Concurrency: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Count(BulkSucceeded))
+	assert.Equal(t, 1, report.Count(BulkFailed))
+	assert.Equal(t, BulkFailed, report.Results[2].Status)
+	assert.Equal(t, "key-user4", report.Results[3].Value.ID)
+	assert.Error(t, report.Err())
+	assert.LessOrEqual(t, maxSeen, 2)
+	assert.Equal(t, int32(5), limiter.waits.Load())
+
+	// Resuming only retries the failed item
+	fail.Store(false)
+	report, err = client.BulkCreateKeys(context.Background(), token, "org1", items, BulkOptions[*Key]{Resume: report})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Count(BulkSucceeded))
+	assert.NoError(t, report.Err())
+	assert.Equal(t, int32(6), limiter.waits.Load())
+
+	// A canceled run leaves the items pending
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err = client.BulkCreateKeys(ctx, token, "org1", items, BulkOptions[*Key]{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 5, report.Count(BulkPending))
+}
@@ -0,0 +1,290 @@
+package akerun
+
+import (
+	"container/list"
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-querystring/query"
+	"golang.org/x/oauth2"
+)
+
+// Default values for CacheConfig.
+const (
+	DefaultCacheOrganizationTTL = 10 * time.Minute
+	DefaultCacheAkerunTTL       = time.Minute
+	DefaultCacheAkerunGroupTTL  = 5 * time.Minute
+	DefaultCacheUserTTL         = 5 * time.Minute
+	DefaultCacheMaxEntries      = 1024
+)
+
+// CacheConfig represents the configuration for CachedClient.
+// A zero TTL disables caching for the resource.
+type CacheConfig struct {
+	OrganizationTTL time.Duration
+	AkerunTTL       time.Duration
+	AkerunGroupTTL  time.Duration
+	UserTTL         time.Duration
+	MaxEntries      int
+}
+
+// NewCacheConfig creates a new cache configuration with the default TTLs.
+func NewCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		OrganizationTTL: DefaultCacheOrganizationTTL,
+		AkerunTTL:       DefaultCacheAkerunTTL,
+		AkerunGroupTTL:  DefaultCacheAkerunGroupTTL,
+		UserTTL:         DefaultCacheUserTTL,
+		MaxEntries:      DefaultCacheMaxEntries,
+	}
+}
+
+// CacheStats represents the hit/miss statistics of CachedClient.
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// cacheEntry represents an entry in the LRU list.
+type cacheEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// CachedClient wraps Client with a read-through cache for reference data.
+// GetOrganization, GetAkeruns, GetAkerunGroups, GetAkerunGroup and GetUser are served from the cache,
+// and mutating calls made through the CachedClient invalidate the affected entries.
+// Reads that are in flight during an invalidation are returned but not cached.
+// The cache is not keyed by token, so a CachedClient should be used for a single identity.
+// Values returned from the cache are shared and must not be modified.
+type CachedClient struct {
+	*Client
+	config *CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+	now     func() time.Time
+	// generation is incremented on every invalidation, so fetches that started earlier are not stored.
+	generation uint64
+}
+
+// NewCachedClient creates a new CachedClient. If config is nil, NewCacheConfig is used.
+func NewCachedClient(client *Client, config *CacheConfig) *CachedClient {
+	if config == nil {
+		config = NewCacheConfig()
+	}
+	return &CachedClient{
+		Client:  client,
+		config:  config,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Stats returns a snapshot of the cache statistics.
+func (c *CachedClient) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// Purge removes all entries from the cache.
+func (c *CachedClient) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Invalidations += uint64(c.lru.Len())
+	c.generation++
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+// get returns the cached value for key if it exists and has not expired.
+func (c *CachedClient) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(elem)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+// currentGeneration returns the generation to pass to set for a fetch that starts now.
+func (c *CachedClient) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// set stores value under key and evicts the least recently used entries beyond MaxEntries.
+// The value is dropped if the cache was invalidated after generation, as it may predate a mutation.
+func (c *CachedClient) set(key string, value interface{}, ttl time.Duration, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	expiresAt := c.now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.config.MaxEntries > 0 && c.lru.Len() > c.config.MaxEntries {
+		c.removeElement(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate removes the entry for p and every entry below it,
+// and keeps fetches that are in flight from storing their results.
+func (c *CachedClient) invalidate(p string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key, elem := range c.entries {
+		if key == p || strings.HasPrefix(key, p+"/") || strings.HasPrefix(key, p+"?") {
+			c.removeElement(elem)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+// removeElement removes elem from the cache. The caller must hold c.mu.
+func (c *CachedClient) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// cachedGet returns the cached value for key, or calls fetch and caches its result.
+func cachedGet[T any](c *CachedClient, key string, ttl time.Duration, fetch func() (*T, error)) (*T, error) {
+	if ttl <= 0 {
+		return fetch()
+	}
+	generation := c.currentGeneration()
+	if v, ok := c.get(key); ok {
+		return v.(*T), nil
+	}
+	result, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, result, ttl, generation)
+	return result, nil
+}
+
+// GetOrganization retrieves the details of an organization, using the cache when possible.
+func (c *CachedClient) GetOrganization(ctx context.Context, oauth2Token *oauth2.Token, id string) (*Organization, error) {
+	key := path.Join(apiPathOrganizations, id)
+	return cachedGet(c, key, c.config.OrganizationTTL, func() (*Organization, error) {
+		return c.Client.GetOrganization(ctx, oauth2Token, id)
+	})
+}
+
+// GetAkeruns retrieves a list of Akeruns, using the cache when possible.
+func (c *CachedClient) GetAkeruns(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, params AkerunListParameter) (*AkerunList, error) {
+	v, err := query.Values(params)
+	if err != nil {
+		return nil, err
+	}
+	key := path.Join(apiPathOrganizations, organizationId, "akeruns") + "?" + v.Encode()
+	return cachedGet(c, key, c.config.AkerunTTL, func() (*AkerunList, error) {
+		return c.Client.GetAkeruns(ctx, oauth2Token, organizationId, params)
+	})
+}
+
+// GetAkerunGroups retrieves a list of Akerun groups, using the cache when possible.
+func (c *CachedClient) GetAkerunGroups(ctx context.Context, oauth2Token *oauth2.Token, organizationId string) (*AkerunGroupList, error) {
+	key := path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup)
+	return cachedGet(c, key, c.config.AkerunGroupTTL, func() (*AkerunGroupList, error) {
+		return c.Client.GetAkerunGroups(ctx, oauth2Token, organizationId)
+	})
+}
+
+// GetAkerunGroup retrieves the details of an Akerun group, using the cache when possible.
+func (c *CachedClient) GetAkerunGroup(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, akerunGroupId string) (*AkerunGroupDetailed, error) {
+	key := path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup, akerunGroupId)
+	return cachedGet(c, key, c.config.AkerunGroupTTL, func() (*AkerunGroupDetailed, error) {
+		return c.Client.GetAkerunGroup(ctx, oauth2Token, organizationId, akerunGroupId)
+	})
+}
+
+// GetUser retrieves the details of a user, using the cache when possible.
+func (c *CachedClient) GetUser(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, userId string) (*User, error) {
+	key := path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId)
+	return cachedGet(c, key, c.config.UserTTL, func() (*User, error) {
+		return c.Client.GetUser(ctx, oauth2Token, organizationId, userId)
+	})
+}
+
+// InviteUser invites a user and invalidates the cached user.
+func (c *CachedClient) InviteUser(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, userId string, params InviteUserParameter) (*User, error) {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId))
+	return c.Client.InviteUser(ctx, oauth2Token, organizationId, userId, params)
+}
+
+// UpdateUser updates a user and invalidates the cached user.
+func (c *CachedClient) UpdateUser(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, userId string, params UpdateUserParameter) (*User, error) {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId))
+	return c.Client.UpdateUser(ctx, oauth2Token, organizationId, userId, params)
+}
+
+// ExitUser removes a user from the organization and invalidates the cached user.
+func (c *CachedClient) ExitUser(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, userId string) error {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId))
+	return c.Client.ExitUser(ctx, oauth2Token, organizationId, userId)
+}
+
+// CreateAkerunGroup creates an Akerun group and invalidates the cached Akerun groups.
+func (c *CachedClient) CreateAkerunGroup(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, params AkerunGroupCreateParameter) (*AkerunGroup, error) {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup))
+	return c.Client.CreateAkerunGroup(ctx, oauth2Token, organizationId, params)
+}
+
+// UpdateAkerunGroup updates an Akerun group and invalidates the cached Akerun groups.
+func (c *CachedClient) UpdateAkerunGroup(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, akerunGroupId string, params AkerunGroupUpdateParameter) (*AkerunGroup, error) {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup))
+	return c.Client.UpdateAkerunGroup(ctx, oauth2Token, organizationId, akerunGroupId, params)
+}
+
+// DeleteAkerunGroup deletes an Akerun group and invalidates the cached Akerun groups.
+func (c *CachedClient) DeleteAkerunGroup(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, akerunGroupId string) error {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup))
+	return c.Client.DeleteAkerunGroup(ctx, oauth2Token, organizationId, akerunGroupId)
+}
+
+// AddAkerunToGroup adds Akeruns to a group and invalidates the cached Akerun groups.
+func (c *CachedClient) AddAkerunToGroup(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, akerunGroupId string, akerunIds ...string) error {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup))
+	return c.Client.AddAkerunToGroup(ctx, oauth2Token, organizationId, akerunGroupId, akerunIds...)
+}
+
+// RemoveAkerunFromGroup removes Akeruns from a group and invalidates the cached Akerun groups.
+func (c *CachedClient) RemoveAkerunFromGroup(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, akerunGroupId string, akerunIds ...string) error {
+	defer c.invalidate(path.Join(apiPathOrganizations, organizationId, apiPathAkerunGroup))
+	return c.Client.RemoveAkerunFromGroup(ctx, oauth2Token, organizationId, akerunGroupId, akerunIds...)
+}
@@ -0,0 +1,153 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestCachedClient_GetUser(t *testing.T) {
+	calls := 0
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, err := w.Write([]byte(`{"user":{"id":"user1","name":"Test User"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	client := NewCachedClient(NewClient(config), nil)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	token := &oauth2.Token{AccessToken: "test_token"}
+	for i := 0; i < 3; i++ {
+		user, err := client.GetUser(context.Background(), token, "org1", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Test User", user.Name)
+	}
+	assert.Equal(t, 1, calls)
+
+	// Expired entries are fetched again
+	now = now.Add(DefaultCacheUserTTL)
+	_, err := client.GetUser(context.Background(), token, "org1", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// Mutating calls invalidate the entry
+	_, err = client.UpdateUser(context.Background(), token, "org1", "user1", UpdateUserParameter{UserName: "Test User"})
+	assert.NoError(t, err)
+	_, err = client.GetUser(context.Background(), token, "org1", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Invalidations)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestCachedClient_Eviction(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"organization":{"id":"org","name":"Test Org"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	cacheConfig := NewCacheConfig()
+	cacheConfig.MaxEntries = 2
+	client := NewCachedClient(NewClient(config), cacheConfig)
+
+	token := &oauth2.Token{AccessToken: "test_token"}
+	for _, id := range []string{"org1", "org2", "org1", "org3"} {
+		_, err := client.GetOrganization(context.Background(), token, id)
+		assert.NoError(t, err)
+	}
+
+	// org2 is the least recently used entry and must have been evicted
+	_, ok := client.get("organizations/org2")
+	assert.False(t, ok)
+	_, ok = client.get("organizations/org1")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), client.Stats().Evictions)
+}
+
+func TestCachedClient_StaleFetch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		name    = "Old Name"
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	// Create a test server whose first GET reads the user and then waits until the update has finished
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current := name
+		if r.Method != http.MethodGet {
+			name = "New Name"
+		}
+		mu.Unlock()
+
+		if r.Method == http.MethodGet && started != nil {
+			close(started)
+			started = nil
+			<-release
+		}
+		_, err := w.Write([]byte(`{"user":{"id":"user1","name":"` + current + `"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	client := NewCachedClient(NewClient(config), nil)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	wait := started
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		user, err := client.GetUser(context.Background(), token, "org1", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Old Name", user.Name)
+	}()
+	<-wait
+
+	_, err := client.UpdateUser(context.Background(), token, "org1", "user1", UpdateUserParameter{UserName: "New Name"})
+	assert.NoError(t, err)
+	close(release)
+	<-done
+
+	// The fetch that started before the update must not bring the old user back into the cache
+	user, err := client.GetUser(context.Background(), token, "org1", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, "New Name", user.Name)
+}
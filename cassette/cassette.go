@@ -0,0 +1,314 @@
+// Package cassette records HTTP interactions with the Akerun API into files and replays them in tests.
+//
+// A Recorder is an http.RoundTripper. Plug it into the client through akerun.Config.HTTPClient:
+//
+//	rec, err := cassette.New("testdata/users.json", cassette.ModeReplay)
+//	config.HTTPClient = rec.Client()
+//	defer rec.Stop()
+//
+// Secrets and personal data are scrubbed before interactions are written,
+// and requests are matched on method, path and query.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Version is the version of the cassette file format.
+const Version = 1
+
+// Redacted replaces the scrubbed values.
+const Redacted = "REDACTED"
+
+// DefaultScrubFields are the query parameters and JSON fields scrubbed by default.
+var DefaultScrubFields = []string{
+	"access_token",
+	"refresh_token",
+	"token",
+	"client_secret",
+	"key_url_password",
+	"key_url",
+	"name",
+	"mail",
+	"image_url",
+	"user_name",
+	"user_mail",
+	"user_image",
+}
+
+// Mode represents the mode of a Recorder.
+type Mode int
+
+// Recorder modes
+const (
+	// ModeReplay replays the interactions of an existing cassette and never sends requests.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request and records the interactions into a new cassette.
+	ModeRecord
+)
+
+// ErrNoInteraction is returned in replay mode when no recorded interaction matches a request.
+var ErrNoInteraction = errors.New("cassette: no matching interaction")
+
+// Request represents a recorded request.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response represents a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Interaction represents a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette represents the contents of a cassette file.
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions.
+type Recorder struct {
+	// Transport sends the requests in record mode. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mode     Mode
+	path     string
+	scrubber scrubber
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a new Recorder for the cassette file at path.
+// In replay mode the file is loaded; in record mode it is written by Stop.
+// The fields in scrubFields are scrubbed in addition to DefaultScrubFields.
+func New(path string, mode Mode, scrubFields ...string) (*Recorder, error) {
+	r := &Recorder{
+		mode:     mode,
+		path:     path,
+		scrubber: newScrubber(append(append([]string{}, DefaultScrubFields...), scrubFields...)),
+		cassette: Cassette{Version: Version},
+	}
+
+	if mode == ModeReplay {
+		byt, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(byt, &r.cassette); err != nil {
+			return nil, err
+		}
+		if r.cassette.Version != Version {
+			return nil, fmt.Errorf("cassette: unsupported version %d", r.cassette.Version)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Client returns an HTTP client that uses the recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop writes the recorded interactions to the cassette file in record mode.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	byt, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, byt, 0o644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.request(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+// request returns the scrubbed form of req used for recording and matching.
+func (r *Recorder) request(req *http.Request) (Request, error) {
+	var body []byte
+	if req.Body != nil && req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return Request{}, err
+		}
+		defer rc.Close()
+		if body, err = io.ReadAll(rc); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  r.scrubber.values(req.URL.Query()).Encode(),
+		Body:   r.scrubber.body(body),
+	}, nil
+}
+
+// replay returns the first unused interaction matching the request.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewBufferString(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s?%s", ErrNoInteraction, recorded.Method, recorded.Path, recorded.Query)
+}
+
+// record sends the request and records the interaction.
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := res.Header.Clone()
+	header.Del("Set-Cookie")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     header,
+			Body:       r.scrubber.body(body),
+		},
+	})
+	return res, nil
+}
+
+// matches reports whether a recorded request matches an incoming one.
+func matches(recorded, req Request) bool {
+	return recorded.Method == req.Method && recorded.Path == req.Path && recorded.Query == req.Query
+}
+
+// scrubber replaces the values of sensitive fields.
+type scrubber map[string]bool
+
+// newScrubber creates a scrubber for fields.
+func newScrubber(fields []string) scrubber {
+	s := scrubber{}
+	for _, f := range fields {
+		s[f] = true
+	}
+	return s
+}
+
+// values returns a copy of v with the sensitive parameters scrubbed.
+func (s scrubber) values(v url.Values) url.Values {
+	r := url.Values{}
+	for key, values := range v {
+		if s[key] {
+			r[key] = []string{Redacted}
+			continue
+		}
+		r[key] = values
+	}
+	return r
+}
+
+// body returns body with the sensitive fields scrubbed.
+// JSON and URL-encoded bodies are supported; other bodies are dropped.
+func (s scrubber) body(body []byte) string {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(trimmed, &v); err == nil {
+		byt, err := json.Marshal(s.json(v))
+		if err != nil {
+			return ""
+		}
+		return string(byt)
+	}
+	if values, err := url.ParseQuery(string(trimmed)); err == nil {
+		return s.values(values).Encode()
+	}
+	return ""
+}
+
+// json scrubs a decoded JSON value recursively.
+// Null values are kept so that recorded payloads keep their shape.
+func (s scrubber) json(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s[key] && value != nil {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = s.json(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = s.json(value)
+		}
+	}
+	return v
+}
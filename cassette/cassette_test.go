@@ -0,0 +1,66 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestRecorder(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/organizations/org1/users", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"users":[{"id":"user1","name":"Taro Yamada","mail":"taro@example.com","code":"E001"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	path := filepath.Join(t.TempDir(), "users.json")
+	token := &oauth2.Token{AccessToken: "secret_token"}
+	params := akerun.UsersParameter{Limit: 10, UserMail: "taro@example.com"}
+
+	// Record the interaction against the server
+	rec, err := New(path, ModeRecord)
+	assert.NoError(t, err)
+	config := akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.HTTPClient = rec.Client()
+	users, err := akerun.NewClient(config).GetUsers(context.Background(), token, "org1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, "Taro Yamada", users.Users[0].Name)
+	assert.NoError(t, rec.Stop())
+	ts.Close()
+
+	byt, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(byt), "secret_token")
+	assert.NotContains(t, string(byt), "Taro Yamada")
+	assert.NotContains(t, string(byt), "taro@example.com")
+
+	// Replay it without the server
+	rec, err = New(path, ModeReplay)
+	assert.NoError(t, err)
+	config.HTTPClient = rec.Client()
+	users, err = akerun.NewClient(config).GetUsers(context.Background(), token, "org1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", users.Users[0].ID)
+	assert.Equal(t, "E001", users.Users[0].Code)
+	assert.Equal(t, Redacted, users.Users[0].Name)
+
+	// Each interaction is replayed once, and unknown requests fail
+	_, err = akerun.NewClient(config).GetUsers(context.Background(), token, "org1", params)
+	assert.True(t, errors.Is(err, ErrNoInteraction))
+}
@@ -1,15 +1,19 @@
 package akerun
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"reflect"
+	"time"
 
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/oauth2"
 )
 
@@ -25,6 +29,48 @@ const (
 type Config struct {
 	APIUrl string
 	Oauth2 *oauth2.Config
+
+	// HTTPClient is used to send API and token requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// TracerProvider is used to create a span per API call and token refresh. If nil, no spans are recorded.
+	// Requests are never retried, so the http.request.resend_count attribute of a span is always 0.
+	TracerProvider trace.TracerProvider
+	// MeterProvider is used to record request latency and error metrics. If nil, no metrics are recorded.
+	MeterProvider metric.MeterProvider
+
+	// Logger is used to log every request and response. If nil, nothing is logged.
+	// Secrets such as the Authorization header and tokens are redacted.
+	Logger *slog.Logger
+	// LogBodies enables logging of the request and response bodies.
+	LogBodies bool
+
+	// Interceptors are called around every API call, the first one being the outermost.
+	Interceptors []Interceptor
+
+	// RateLimiter is waited on before every API request. If nil, requests are not limited.
+	RateLimiter RateLimiter
+
+	// Encodings overrides how the parameters of operations are sent. Operations that create or
+	// update resources send a form body by default; all others use the query string.
+	Encodings map[OperationName]Encoding
+
+	// StrictDecoding makes API calls fail with a *SchemaError when a response has fields the models
+	// do not know or lacks fields they expect. The methods then return the error and no result,
+	// although interceptors still find the decoded response in Operation.Result. Use it in tests to notice API changes.
+	StrictDecoding bool
+
+	// DryRun prevents mutating requests from being sent. They are logged, passed to OnDryRun
+	// and answered with a result synthesized from the request parameters. GET requests are still sent.
+	// Token revocations are mutating requests too, so they are skipped and TokenManager.Revoke keeps the tokens.
+	DryRun bool
+	// OnDryRun is called with every request skipped in dry-run mode.
+	OnDryRun func(ctx context.Context, req DryRunRequest)
+}
+
+// RateLimiter limits the rate of API requests. *rate.Limiter of golang.org/x/time/rate satisfies it.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
 }
 
 // Error represents an error returned by the Akerun API.
@@ -72,7 +118,10 @@ func NewConfig(clientID, clientSecret, redirectURL string) *Config {
 
 // Client represents the Akerun client.
 type Client struct {
-	config *Config
+	config    *Config
+	tel       telemetryHolder
+	rateLimit rateLimitState
+	tokens    tokenCache
 }
 
 // NewClient creates a new Akerun client.
@@ -80,48 +129,83 @@ func NewClient(config *Config) *Client {
 	return &Client{config: config}
 }
 
-// call_version calls the specified API endpoint with the given method, OAuth2 token, query parameters, post body, and response object.
+// call_version calls the specified API endpoint with the given method, OAuth2 token, parameters, and response object.
 // It returns an error if the call fails.
 func (c *Client) callVersion(
 	ctx context.Context,
+	name OperationName,
 	apiEndpoint string,
 	method string,
 	oauth2Token *oauth2.Token,
-	queryParams url.Values,
-	postBody interface{},
+	params url.Values,
 	res interface{},
 ) error {
 	path := path.Join(APIVerison, apiEndpoint)
-	return c.call(ctx, path, method, oauth2Token, queryParams, postBody, res)
+	return c.call(ctx, name, path, method, oauth2Token, params, res)
+}
+
+// envelope represents a result that the API wraps in an object under a single key, e.g. {"user": {...}}.
+type envelope struct {
+	key    string
+	result interface{}
+}
+
+// enveloped returns a result for call that decodes the value under key into result.
+func enveloped(key string, result interface{}) envelope {
+	return envelope{key: key, result: result}
 }
 
-// call sends a request to the Akerun API.
+// call sends a request to the Akerun API through the configured interceptors.
+// The parameters are sent in the query string or the body depending on the encoding of the operation.
 func (c *Client) call(
 	ctx context.Context,
+	name OperationName,
 	apiEndpoint string,
 	method string,
 	oauth2Token *oauth2.Token,
-	queryParams url.Values,
-	postBody interface{},
+	params url.Values,
 	res interface{},
 ) error {
-	var (
-		contentType string
-		body        io.Reader
-	)
-	contentType = "application/json"
-	jsonParams, err := json.Marshal(postBody)
+	op := &Operation{
+		Name:     name,
+		Method:   method,
+		Path:     apiEndpoint,
+		Encoding: c.encoding(name),
+		Header:   http.Header{},
+		Token:    oauth2Token,
+		Result:   res,
+	}
+	if e, ok := res.(envelope); ok {
+		op.Result = e.result
+		op.envelope = e.key
+	}
+	if op.Encoding == EncodingQuery {
+		op.Query = params
+	} else if len(params) > 0 {
+		op.Body = params
+	}
+	return c.roundTrip()(ctx, op)
+}
+
+// send encodes an operation into an HTTP request and sends it.
+func (c *Client) send(ctx context.Context, op *Operation) error {
+	body, contentType, err := encodeBody(op)
 	if err != nil {
 		return err
 	}
 
-	body = bytes.NewBuffer(jsonParams)
-	req, err := c.newRequest(ctx, apiEndpoint, method, contentType, queryParams, body)
+	req, err := c.newRequest(ctx, op.Path, op.Method, contentType, op.Query, body)
 	if err != nil {
 		return err
 	}
+	for key, values := range op.Header {
+		req.Header[key] = values
+	}
 
-	return c.do(ctx, oauth2Token, req, res)
+	if c.config.DryRun && op.Method != http.MethodGet {
+		return c.dryRun(ctx, op, req)
+	}
+	return c.do(ctx, op.Token, req, op.envelope, op.Result)
 }
 
 // newRequest creates a new HTTP request for the Akerun API.
@@ -154,34 +238,121 @@ func (c *Client) newRequest(
 }
 
 // do sends an HTTP request to the Akerun API.
+// The request is authorized with oauth2Token, refreshing it if needed, unless oauth2Token is nil.
 func (c *Client) do(
 	ctx context.Context,
 	oauth2Token *oauth2.Token,
 	req *http.Request,
+	key string,
 	res interface{},
-) error {
-	tokenSource := c.config.Oauth2.TokenSource(ctx, oauth2Token)
-	httpClient := oauth2.NewClient(ctx, tokenSource)
-	response, err := httpClient.Do(req)
+) (err error) {
+	var (
+		code int
+		body []byte
+	)
+	start := time.Now()
+	ctx, finish := c.startRequestSpan(ctx, req)
+	defer func() {
+		finish(code, err)
+		c.logRequest(ctx, req, code, body, time.Since(start), err)
+	}()
+
+	if c.config.RateLimiter != nil {
+		if err = c.config.RateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	ctx = c.httpContext(ctx)
+	if oauth2Token != nil {
+		var token *oauth2.Token
+		token, err = c.authToken(ctx, oauth2Token)
+		if err != nil {
+			return err
+		}
+		token.SetAuthHeader(req)
+	}
+	response, err := c.httpClient().Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
 
 	defer response.Body.Close()
 
-	var r io.Reader = response.Body
+	code = response.StatusCode
+	body, err = io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	c.recordResponse(ctx, newResponse(response, body, time.Now()))
 
-	code := response.StatusCode
 	if code >= http.StatusBadRequest {
-		byt, _ := io.ReadAll(r)
 		return &Error{
 			StatusCode: code,
-			RawError:   string(byt),
+			RawError:   string(body),
 		}
 	}
 
 	if res == nil {
 		return nil
 	}
-	return json.NewDecoder(r).Decode(&res)
+	return c.decode(body, key, res)
+}
+
+// decode decodes the response body into res. If key is not empty, the value under key is decoded.
+func (c *Client) decode(body []byte, key string, res interface{}) error {
+	data := body
+	if key != "" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return err
+		}
+		data = fields[key]
+		if data == nil {
+			data = []byte("null")
+		}
+	}
+	if err := json.Unmarshal(data, res); err != nil {
+		return err
+	}
+	if !c.config.StrictDecoding {
+		return nil
+	}
+	if key == "" {
+		return CheckSchema(body, res)
+	}
+	// Check the whole body against the envelope, so that unknown fields next to key are reported too.
+	wrapper := reflect.StructOf([]reflect.StructField{{
+		Name: "Result",
+		Type: reflect.TypeOf(res),
+		Tag:  reflect.StructTag(`json:"` + key + `"`),
+	}})
+	return CheckSchema(body, reflect.New(wrapper).Interface())
+}
+
+// tokenSource returns a token source that reuses oauth2Token until it expires
+// and refreshes it with the client configuration.
+func (c *Client) tokenSource(ctx context.Context, oauth2Token *oauth2.Token) oauth2.TokenSource {
+	var refreshToken string
+	if oauth2Token != nil {
+		refreshToken = oauth2Token.RefreshToken
+	}
+	refresher := c.config.Oauth2.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken})
+	return oauth2.ReuseTokenSource(oauth2Token, &tracingTokenSource{ctx: ctx, src: refresher, t: c.telemetry()})
+}
+
+// httpClient returns the configured HTTP client.
+func (c *Client) httpClient() *http.Client {
+	if c.config.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.config.HTTPClient
+}
+
+// httpContext returns ctx carrying the configured HTTP client for the oauth2 package.
+func (c *Client) httpContext(ctx context.Context) context.Context {
+	if c.config.HTTPClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, c.config.HTTPClient)
 }
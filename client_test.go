@@ -1,8 +1,15 @@
 package akerun
 
 import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"golang.org/x/oauth2"
 )
@@ -41,3 +48,54 @@ func TestNewClient(t *testing.T) {
 		t.Errorf("NewConfig() = %#v, want %#v", client, expectClient)
 	}
 }
+
+// benchmarkClient runs GetOrganization in parallel and reports the connections opened and tokens refreshed per operation.
+func benchmarkClient(b *testing.B, token *oauth2.Token) {
+	var conns, refreshes atomic.Int64
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			refreshes.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"refreshed","refresh_token":"refresh","token_type":"Bearer","expires_in":7200}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"organization":{"id":"org1","name":"Test Org"}}`))
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := client.GetOrganization(context.Background(), token, "org1"); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+	b.ReportMetric(float64(refreshes.Load())/float64(b.N), "refreshes/op")
+}
+
+func BenchmarkClient_ValidToken(b *testing.B) {
+	benchmarkClient(b, &oauth2.Token{AccessToken: "test_token", Expiry: time.Now().Add(time.Hour)})
+}
+
+func BenchmarkClient_ExpiredToken(b *testing.B) {
+	benchmarkClient(b, &oauth2.Token{AccessToken: "test_token", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)})
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/Hayao0819/go-akerun/internal/listing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace is the prefix of every metric exposed by the exporter.
+const namespace = "akerun"
+
+var (
+	batteryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "battery_percentage"),
+		"Battery percentage of an Akerun device.",
+		[]string{"organization", "akerun", "name", "device", "device_id"}, nil,
+	)
+	autolockDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "autolock_enabled"),
+		"Whether autolock is enabled on an Akerun (1) or not (0).",
+		[]string{"organization", "akerun", "name"}, nil,
+	)
+	devicesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "devices"),
+		"Number of Akeruns in an organization.",
+		[]string{"organization"}, nil,
+	)
+	groupDevicesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "group", "devices"),
+		"Number of Akeruns in an Akerun group.",
+		[]string{"organization", "group", "name"}, nil,
+	)
+	accessEventsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "access_events_total"),
+		"Number of access events observed since the exporter started.",
+		[]string{"organization", "action", "device_type"}, nil,
+	)
+)
+
+// accessKey identifies an access events counter.
+type accessKey struct {
+	organization string
+	action       string
+	deviceType   string
+}
+
+// orgState holds the last collected state of an organization.
+type orgState struct {
+	akeruns []akerun.Akerun
+	groups  []akerun.AkerunGroupDetailed
+
+	// accessCursor is the time accesses have been counted up to,
+	// and accessSeen holds the IDs of the events at that time.
+	accessCursor time.Time
+	accessSeen   map[string]bool
+}
+
+// exporter polls the Akerun API and exposes the collected values as Prometheus metrics.
+type exporter struct {
+	// client takes the token of every request from its token manager, which keeps rotated tokens.
+	client        *akerun.AccountClient
+	organizations []string
+	now           func() time.Time
+
+	mu       sync.RWMutex
+	states   map[string]*orgState
+	accesses map[accessKey]float64
+
+	refreshes       prometheus.Counter
+	refreshErrors   prometheus.Counter
+	orgErrors       *prometheus.GaugeVec
+	refreshDuration prometheus.Histogram
+	lastRefresh     prometheus.Gauge
+}
+
+// newExporter creates a new exporter that calls the API as the account of client. If organizations is empty,
+// every organization the account can access is exported.
+func newExporter(client *akerun.AccountClient, organizations []string) *exporter {
+	return &exporter{
+		client:        client,
+		organizations: organizations,
+		now:           time.Now,
+		states:        map[string]*orgState{},
+		accesses:      map[accessKey]float64{},
+		refreshes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "exporter", Name: "refreshes_total",
+			Help: "Number of polls of the Akerun API.",
+		}),
+		refreshErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "exporter", Name: "refresh_errors_total",
+			Help: "Number of polls of the Akerun API that failed.",
+		}),
+		orgErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "exporter", Name: "organization_refresh_error",
+			Help: "Whether the last poll of an organization failed (1) or not (0).",
+		}, []string{"organization"}),
+		refreshDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "exporter", Name: "refresh_duration_seconds",
+			Help: "Duration of polls of the Akerun API.",
+		}),
+		lastRefresh: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "exporter", Name: "last_refresh_timestamp_seconds",
+			Help: "Unix time of the last successful poll of the Akerun API.",
+		}),
+	}
+}
+
+// run polls the Akerun API every interval until ctx is canceled.
+func (e *exporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.refresh(ctx); err != nil {
+			log.Printf("refresh failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh polls the Akerun API once.
+func (e *exporter) refresh(ctx context.Context) (err error) {
+	start := e.now()
+	e.refreshes.Inc()
+	defer func() {
+		e.refreshDuration.Observe(e.now().Sub(start).Seconds())
+		if err != nil {
+			e.refreshErrors.Inc()
+			return
+		}
+		e.lastRefresh.Set(float64(e.now().Unix()))
+	}()
+
+	// Without a token every organization would fail the same way, so the poll fails as a whole.
+	if _, err = e.client.Token(ctx); err != nil {
+		return err
+	}
+
+	organizations := e.organizations
+	if len(organizations) == 0 {
+		if organizations, err = listing.Organizations(ctx, e.client.Client, nil); err != nil {
+			return err
+		}
+	}
+
+	// A failing organization keeps its last values, and does not stop the others from being refreshed.
+	var errs []error
+	for _, organizationId := range organizations {
+		if err := e.refreshOrganization(ctx, organizationId); err != nil {
+			errs = append(errs, fmt.Errorf("organization %s: %w", organizationId, err))
+			e.orgErrors.WithLabelValues(organizationId).Set(1)
+			continue
+		}
+		e.orgErrors.WithLabelValues(organizationId).Set(0)
+	}
+	return errors.Join(errs...)
+}
+
+// refreshOrganization polls the devices, groups and new access events of an organization.
+func (e *exporter) refreshOrganization(ctx context.Context, organizationId string) error {
+	e.mu.RLock()
+	prev := e.states[organizationId]
+	e.mu.RUnlock()
+
+	// On the first poll of an organization, the access history before now is ignored.
+	state := &orgState{accessCursor: e.now(), accessSeen: map[string]bool{}}
+	if prev != nil {
+		state.accessCursor = prev.accessCursor
+		state.accessSeen = prev.accessSeen
+	}
+
+	var err error
+	if state.akeruns, err = listing.Akeruns(ctx, e.client.Client, nil, organizationId); err != nil {
+		return err
+	}
+	if state.groups, err = listing.AkerunGroups(ctx, e.client.Client, nil, organizationId); err != nil {
+		return err
+	}
+
+	counts, err := e.pollAccesses(ctx, organizationId, state)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.states[organizationId] = state
+	for key, n := range counts {
+		e.accesses[key] += n
+	}
+	return nil
+}
+
+// pollAccesses counts the access events after the cursor of state and advances it.
+func (e *exporter) pollAccesses(ctx context.Context, organizationId string, state *orgState) (map[accessKey]float64, error) {
+	counts := map[accessKey]float64{}
+	cursor := state.accessCursor
+	seen := map[string]bool{}
+
+	accesses, err := listing.Accesses(ctx, e.client.Client, nil, organizationId, akerun.AccessesParameter{
+		DateTimeAfter: state.accessCursor.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accesses {
+		at, err := time.Parse(time.RFC3339, a.AccessedAt)
+		if err != nil || at.Before(state.accessCursor) || state.accessSeen[a.ID] {
+			continue
+		}
+		counts[accessKey{organizationId, a.Action, a.DeviceType}]++
+
+		switch {
+		case at.After(cursor):
+			cursor = at
+			seen = map[string]bool{a.ID: true}
+		case at.Equal(cursor):
+			seen[a.ID] = true
+		}
+	}
+
+	if cursor.Equal(state.accessCursor) {
+		for id := range state.accessSeen {
+			seen[id] = true
+		}
+	}
+	state.accessCursor = cursor
+	state.accessSeen = seen
+	return counts, nil
+}
+
+// Describe implements prometheus.Collector.
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- batteryDesc
+	ch <- autolockDesc
+	ch <- devicesDesc
+	ch <- groupDevicesDesc
+	ch <- accessEventsDesc
+	e.refreshes.Describe(ch)
+	e.refreshErrors.Describe(ch)
+	e.orgErrors.Describe(ch)
+	e.refreshDuration.Describe(ch)
+	e.lastRefresh.Describe(ch)
+}
+
+// Collect implements prometheus.Collector. It only reads the values collected by the last poll.
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for organizationId, state := range e.states {
+		ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(len(state.akeruns)), organizationId)
+
+		for _, a := range state.akeruns {
+			// Devices without an ID are not installed, and an unknown percentage is not reported as empty.
+			battery := func(device, id string, percentage int, unknown bool) {
+				if id == "" || unknown {
+					return
+				}
+				ch <- prometheus.MustNewConstMetric(batteryDesc, prometheus.GaugeValue, float64(percentage),
+					organizationId, a.ID, a.Name, device, id)
+			}
+			battery("lock", a.ID, a.BatteryPercentage, a.UnknownBatteries.Lock)
+			battery("nfc_reader_inside", a.NFCReaderInside.ID, a.NFCReaderInside.BatteryPercentage, a.UnknownBatteries.NFCReaderInside)
+			battery("nfc_reader_outside", a.NFCReaderOutside.ID, a.NFCReaderOutside.BatteryPercentage, a.UnknownBatteries.NFCReaderOutside)
+			battery("door_sensor", a.DoorSensor.ID, a.DoorSensor.BatteryPercentage, a.UnknownBatteries.DoorSensor)
+
+			autolock := 0.0
+			if a.Autolock {
+				autolock = 1
+			}
+			ch <- prometheus.MustNewConstMetric(autolockDesc, prometheus.GaugeValue, autolock, organizationId, a.ID, a.Name)
+		}
+
+		for _, g := range state.groups {
+			ch <- prometheus.MustNewConstMetric(groupDevicesDesc, prometheus.GaugeValue, float64(len(g.Akeruns)), organizationId, g.ID, g.Name)
+		}
+	}
+
+	for key, n := range e.accesses {
+		ch <- prometheus.MustNewConstMetric(accessEventsDesc, prometheus.CounterValue, n, key.organization, key.action, key.deviceType)
+	}
+
+	e.refreshes.Collect(ch)
+	e.refreshErrors.Collect(ch)
+	e.orgErrors.Collect(ch)
+	e.refreshDuration.Collect(ch)
+	e.lastRefresh.Collect(ch)
+}
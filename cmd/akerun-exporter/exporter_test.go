@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// accountClient returns a client for the exporter account whose token is token.
+func accountClient(t *testing.T, token *oauth2.Token) *akerun.AccountClient {
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	manager := akerun.NewTokenManager(client, akerun.NewMemoryTokenStore())
+	if err := manager.SetToken(account, token); err != nil {
+		t.Fatal(err)
+	}
+	return manager.ClientFor(account)
+}
+
+func TestExporter(t *testing.T) {
+	accesses := `{"accesses":[]}`
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/v3/organizations/org1/akeruns":
+			body = `{"akeruns":[{"id":"A1","name":"Door","autolock":true,"battery_percentage":80,"nfc_reader_inside":{"id":"N0","battery_percentage":null},"nfc_reader_outside":{"id":"N1","battery_percentage":50},"door_sensor":{"id":""}}]}`
+		case "/v3/organizations/org1/akerun_groups":
+			body = `{"akerun_groups":[{"id":"G1","name":"Group"}]}`
+		case "/v3/organizations/org1/akerun_groups/G1":
+			body = `{"akerun_group":{"id":"G1","name":"Group","akeruns":[{"id":"A1"}]}}`
+		case "/v3/organizations/org1/accesses":
+			body = accesses
+		case "/v3/organizations/org2/akeruns":
+			w.WriteHeader(http.StatusInternalServerError)
+			body = `{"message":"internal error"}`
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	e := newExporter(accountClient(t, &oauth2.Token{AccessToken: "test_token", Expiry: time.Now().Add(time.Hour)}), []string{"org2", "org1"})
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	// The first poll ignores the access history and only sets the cursor.
+	// org2 fails on every poll, which does not keep org1 from being refreshed.
+	assert.ErrorContains(t, e.refresh(context.Background()), "organization org2")
+
+	// Events after the cursor are counted once, even if they are returned again
+	accesses = `{"accesses":[{"id":"1","action":"unlock","device_type":"nfc_outside","accessed_at":"2024-01-01T09:00:10Z"},{"id":"2","action":"unlock","device_type":"nfc_outside","accessed_at":"2024-01-01T09:00:20Z"}]}`
+	assert.Error(t, e.refresh(context.Background()))
+	assert.Error(t, e.refresh(context.Background()))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	expected := `
+# HELP akerun_access_events_total Number of access events observed since the exporter started.
+# TYPE akerun_access_events_total counter
+akerun_access_events_total{action="unlock",device_type="nfc_outside",organization="org1"} 2
+# HELP akerun_autolock_enabled Whether autolock is enabled on an Akerun (1) or not (0).
+# TYPE akerun_autolock_enabled gauge
+akerun_autolock_enabled{akerun="A1",name="Door",organization="org1"} 1
+# HELP akerun_battery_percentage Battery percentage of an Akerun device.
+# TYPE akerun_battery_percentage gauge
+akerun_battery_percentage{akerun="A1",device="lock",device_id="A1",name="Door",organization="org1"} 80
+akerun_battery_percentage{akerun="A1",device="nfc_reader_outside",device_id="N1",name="Door",organization="org1"} 50
+# HELP akerun_group_devices Number of Akeruns in an Akerun group.
+# TYPE akerun_group_devices gauge
+akerun_group_devices{group="G1",name="Group",organization="org1"} 1
+# HELP akerun_exporter_refreshes_total Number of polls of the Akerun API.
+# TYPE akerun_exporter_refreshes_total counter
+akerun_exporter_refreshes_total 3
+# HELP akerun_exporter_organization_refresh_error Whether the last poll of an organization failed (1) or not (0).
+# TYPE akerun_exporter_organization_refresh_error gauge
+akerun_exporter_organization_refresh_error{organization="org1"} 0
+akerun_exporter_organization_refresh_error{organization="org2"} 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"akerun_access_events_total",
+		"akerun_autolock_enabled",
+		"akerun_battery_percentage",
+		"akerun_group_devices",
+		"akerun_exporter_refreshes_total",
+		"akerun_exporter_organization_refresh_error",
+	)
+	assert.NoError(t, err)
+}
+
+func TestExporter_AllOrganizations(t *testing.T) {
+	var refreshed []string
+	// Serve 101 organizations over two pages
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch {
+		case r.URL.Path == "/v3/organizations":
+			var ids []string
+			if r.URL.Query().Get("id_after") == "" {
+				for i := 0; i < 100; i++ {
+					ids = append(ids, fmt.Sprintf(`{"id":"org%d"}`, i))
+				}
+			} else {
+				ids = append(ids, `{"id":"org100"}`)
+			}
+			body = `{"organizations":[` + strings.Join(ids, ",") + `]}`
+		case strings.HasSuffix(r.URL.Path, "/akeruns"):
+			refreshed = append(refreshed, strings.Split(r.URL.Path, "/")[3])
+			body = `{"akeruns":[]}`
+		case strings.HasSuffix(r.URL.Path, "/akerun_groups"):
+			body = `{"akerun_groups":[]}`
+		case strings.HasSuffix(r.URL.Path, "/accesses"):
+			body = `{"accesses":[]}`
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	e := newExporter(accountClient(t, &oauth2.Token{AccessToken: "test_token", Expiry: time.Now().Add(time.Hour)}), nil)
+
+	assert.NoError(t, e.refresh(context.Background()))
+	assert.Len(t, refreshed, 101)
+	assert.Equal(t, "org100", refreshed[100])
+}
+
+func TestExporter_TokenExpiresDuringPoll(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		refreshes []string
+	)
+	// Create a test server to mock the API and token responses
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/oauth/token":
+			assert.NoError(t, r.ParseForm())
+			mu.Lock()
+			refreshes = append(refreshes, r.PostForm.Get("refresh_token"))
+			n := len(refreshes)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			body = fmt.Sprintf(`{"access_token":"access%d","refresh_token":"refresh%d","token_type":"Bearer","expires_in":7200}`, n, n)
+		case "/v3/organizations/org1/akeruns":
+			// The token expires while the Akeruns are listed
+			time.Sleep(300 * time.Millisecond)
+			body = `{"akeruns":[]}`
+		case "/v3/organizations/org1/akerun_groups":
+			body = `{"akerun_groups":[]}`
+		case "/v3/organizations/org1/accesses":
+			body = `{"accesses":[]}`
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	// Tokens are considered expired 10 seconds before their expiry
+	client := accountClient(t, &oauth2.Token{AccessToken: "access0", RefreshToken: "refresh0", Expiry: time.Now().Add(10*time.Second + 200*time.Millisecond)})
+	e := newExporter(client, []string{"org1"})
+	assert.NoError(t, e.refresh(context.Background()))
+
+	// The token refreshed during the poll is the one kept by the manager
+	token, err := client.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh1", token.RefreshToken)
+	assert.Equal(t, []string{"refresh0"}, refreshes)
+}
@@ -0,0 +1,127 @@
+// Command akerun-exporter exposes Prometheus metrics about the Akerun devices of one or more organizations.
+//
+// The exporter polls the Akerun API in the background and serves the last collected values,
+// so scrapes never reach the API. Credentials are read from the environment:
+//
+//	CLIENT_ID, CLIENT_SECRET       OAuth2 application credentials
+//	REDIRECT_URL                   OAuth2 redirect URL registered for the application
+//	AKERUN_REFRESH_TOKEN           OAuth2 refresh token, used when the token file has no token yet
+//	AKERUN_TOKEN_PASSPHRASE        Passphrase of the token file, unless -token-key-file is given
+//
+// Tokens are kept in the encrypted token file given by -token-file. The Akerun API rotates refresh tokens,
+// so every refreshed token is written back to the file and used after a restart instead of AKERUN_REFRESH_TOKEN.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/oauth2"
+)
+
+// account is the name the exporter stores its token under in the token file.
+const account = "exporter"
+
+func main() {
+	var (
+		listenAddress = flag.String("listen-address", ":9826", "Address to expose metrics on.")
+		metricsPath   = flag.String("metrics-path", "/metrics", "Path to expose metrics on.")
+		interval      = flag.Duration("interval", time.Minute, "Interval between polls of the Akerun API.")
+		organizations = flag.String("organizations", "", "Comma-separated organization IDs. Defaults to every organization the token can access.")
+		tokenFile     = flag.String("token-file", "akerun-exporter.tokens", "Path of the encrypted file the tokens are kept in.")
+		tokenKeyFile  = flag.String("token-key-file", "", "Path of a base64-encoded 32-byte key for the token file. Defaults to AKERUN_TOKEN_PASSPHRASE.")
+	)
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config := akerun.NewConfig(os.Getenv("CLIENT_ID"), os.Getenv("CLIENT_SECRET"), os.Getenv("REDIRECT_URL"))
+	client := akerun.NewClient(config)
+
+	key, err := tokenKey(*tokenKeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := akerun.NewEncryptedFileTokenStore(*tokenFile, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	manager := akerun.NewTokenManager(client, store)
+	if err := seedToken(manager, os.Getenv("AKERUN_REFRESH_TOKEN")); err != nil {
+		log.Fatal(err)
+	}
+
+	var orgs []string
+	if *organizations != "" {
+		orgs = strings.Split(*organizations, ",")
+	}
+
+	exporter := newExporter(manager.ClientFor(account), orgs)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		exporter,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	go exporter.run(ctx, *interval)
+
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: *listenAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("listening on %s", *listenAddress)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// tokenKey returns the key of the token file, read from keyFile or derived from AKERUN_TOKEN_PASSPHRASE.
+func tokenKey(keyFile string) (akerun.EncryptionKey, error) {
+	if keyFile != "" {
+		return akerun.KeyFromFile(keyFile)
+	}
+	passphrase := os.Getenv("AKERUN_TOKEN_PASSPHRASE")
+	if passphrase == "" {
+		return akerun.EncryptionKey{}, errors.New("either -token-key-file or AKERUN_TOKEN_PASSPHRASE is required")
+	}
+	return akerun.PassphraseKey(passphrase), nil
+}
+
+// seedToken stores refreshToken as the token of the exporter if the store has none yet.
+// A stored token is kept, since its refresh token has replaced refreshToken once it was used.
+func seedToken(manager *akerun.TokenManager, refreshToken string) error {
+	accounts, err := manager.Accounts()
+	if err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		if a == account {
+			return nil
+		}
+	}
+	if refreshToken == "" {
+		return errors.New("AKERUN_REFRESH_TOKEN is required while the token file has no token")
+	}
+	// An access token without an expiry would be used forever, so only the refresh token is given
+	return manager.SetToken(account, &oauth2.Token{RefreshToken: refreshToken})
+}
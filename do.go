@@ -0,0 +1,46 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Do calls an arbitrary Akerun API endpoint, for endpoints this package does not wrap yet.
+// It goes through the same authorization, interceptors, rate limiting, dry-run handling,
+// logging, telemetry and error handling as the other methods.
+//
+// apiPath is the path including the API version, e.g. "/v3/organizations/O1/akeruns".
+// query is sent in the query string. body is sent as an application/x-www-form-urlencoded body
+// if it is url.Values and as a JSON body otherwise; a nil body sends none.
+// If out is not nil, the JSON response is decoded into it. Errors of the API are returned as *Error.
+func (c *Client) Do(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	method string,
+	apiPath string,
+	query url.Values,
+	body interface{},
+	out interface{},
+) error {
+	op := &Operation{
+		Name:   OpDo,
+		Method: method,
+		Path:   "/" + strings.TrimPrefix(apiPath, "/"),
+		Query:  query,
+		Header: http.Header{},
+		Token:  oauth2Token,
+		Result: out,
+	}
+	if body != nil {
+		op.Body = body
+		op.Encoding = EncodingJSON
+		if _, ok := body.(url.Values); ok {
+			op.Encoding = EncodingForm
+		}
+	}
+	return c.roundTrip()(ctx, op)
+}
@@ -0,0 +1,80 @@
+package akerun
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_Do(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test_token", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/v3/organizations/org1/akerun_remotes":
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "limit=10", r.URL.RawQuery)
+			assert.Empty(t, body)
+			_, err = w.Write([]byte(`{"akerun_remotes":[{"id":"R1"}]}`))
+		case "/v3/organizations/org1/webhooks":
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.JSONEq(t, `{"url":"https://example.com/hook"}`, string(body))
+			_, err = w.Write([]byte(`{"webhook":{"id":"W1"}}`))
+		case "/v3/organizations/org1/notes":
+			assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+			assert.Equal(t, "text=hello", string(body))
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, err = w.Write([]byte(`{"message":"invalid"}`))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	var ops []OperationName
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Interceptors = []Interceptor{func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, op *Operation) error {
+			ops = append(ops, op.Name)
+			return next(ctx, op)
+		}
+	}}
+	client := NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	var remotes struct {
+		AkerunRemotes []struct {
+			ID string `json:"id"`
+		} `json:"akerun_remotes"`
+	}
+	err := client.Do(context.Background(), token, http.MethodGet, "v3/organizations/org1/akerun_remotes", url.Values{"limit": {"10"}}, nil, &remotes)
+	assert.NoError(t, err)
+	assert.Equal(t, "R1", remotes.AkerunRemotes[0].ID)
+
+	var webhook map[string]map[string]string
+	err = client.Do(context.Background(), token, http.MethodPost, "/v3/organizations/org1/webhooks", nil, map[string]string{"url": "https://example.com/hook"}, &webhook)
+	assert.NoError(t, err)
+	assert.Equal(t, "W1", webhook["webhook"]["id"])
+
+	err = client.Do(context.Background(), token, http.MethodPost, "/v3/organizations/org1/notes", nil, url.Values{"text": {"hello"}}, nil)
+	var apiErr *Error
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+
+	assert.Equal(t, []OperationName{OpDo, OpDo, OpDo}, ops)
+}
@@ -0,0 +1,118 @@
+package akerun
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// DryRunID is the ID given to resources synthesized in dry-run mode,
+// and the key URL of synthesized keys that enable one.
+const DryRunID = "dry-run"
+
+// DryRunRequest represents a request that was not sent because the client is in dry-run mode.
+type DryRunRequest struct {
+	Operation OperationName
+	Method    string
+	URL       string
+	Header    http.Header
+	Body      []byte
+}
+
+// dryRun records a mutating request instead of sending it and synthesizes its result.
+func (c *Client) dryRun(ctx context.Context, op *Operation, req *http.Request) error {
+	r := DryRunRequest{
+		Operation: op.Name,
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Header:    req.Header.Clone(),
+		Body:      requestBody(req),
+	}
+
+	if c.config.Logger != nil {
+		c.config.Logger.LogAttrs(ctx, slog.LevelInfo, "akerun dry run",
+			slog.String("operation", string(op.Name)),
+			slog.String("method", r.Method),
+			slog.String("url", redactURL(req.URL)),
+			slog.String("request_body", redactBody(r.Body)),
+		)
+	}
+	if c.config.OnDryRun != nil {
+		c.config.OnDryRun(ctx, r)
+	}
+
+	synthesizeResult(op)
+	return nil
+}
+
+// synthesizeResult fills the result of a mutating operation from its parameters,
+// as the API would have returned it.
+func synthesizeResult(op *Operation) {
+	q := operationParams(op)
+	id := pathID(op.Path)
+
+	switch res := op.Result.(type) {
+	case *User:
+		*res = User{
+			ID:        id,
+			Name:      q.Get("user_name"),
+			Mail:      q.Get("user_mail"),
+			ImageUrl:  q.Get("user_image"),
+			Authority: q.Get("user_authority"),
+			Code:      q.Get("user_code"),
+		}
+	case *Key:
+		key := Key{ID: id, Role: q.Get("role"), ScheduleType: q.Get("schedule_type")}
+		key.TemporarySchedule.StartDateTime = q.Get("temporary_schedule[start_datetime]")
+		key.TemporarySchedule.EndDateTime = q.Get("temporary_schedule[end_datetime]")
+		for _, day := range q["recurring_schedule[days_of_week]"] {
+			if d, err := strconv.ParseUint(day, 10, 32); err == nil {
+				key.RecurringSchedule.DaysOfWeek = append(key.RecurringSchedule.DaysOfWeek, uint32(d))
+			}
+		}
+		key.RecurringSchedule.StartTime = q.Get("recurring_schedule[start_time]")
+		key.RecurringSchedule.EndTime = q.Get("recurring_schedule[end_time]")
+		if enabled, _ := strconv.ParseBool(q.Get("enable_key_url")); enabled {
+			key.Keys.KeyUrl = DryRunID
+		}
+		key.Keys.PasswordProtected = q.Get("key_url_password") != ""
+		key.Akerun.ID = q.Get("akerun_id")
+		key.User.ID = q.Get("user_id")
+		*res = key
+	case *AkerunGroup:
+		*res = AkerunGroup{ID: id, Name: q.Get("name"), Memo: q.Get("memo")}
+	case *AkerunGroupDetailed:
+		*res = AkerunGroupDetailed{ID: pathID(path.Dir(op.Path))}
+	}
+}
+
+// operationParams returns the parameters of an operation, whether they are sent in the query string or the body.
+func operationParams(op *Operation) url.Values {
+	params := url.Values{}
+	for key, values := range op.Query {
+		params[key] = values
+	}
+	if body, ok := op.Body.(url.Values); ok {
+		for key, values := range body {
+			params[key] = values
+		}
+	}
+	return params
+}
+
+// pathID returns the ID at the end of an API path, or DryRunID if the path ends with a collection.
+func pathID(p string) string {
+	switch base := path.Base(p); base {
+	case apiPathUsers, apiPathKeys, apiPathAkerunGroup, "akeruns", "revoke":
+		return DryRunID
+	default:
+		id, err := url.PathUnescape(base)
+		if err != nil {
+			return base
+		}
+		return id
+	}
+}
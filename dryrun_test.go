@@ -0,0 +1,82 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_DryRun(t *testing.T) {
+	var methods []string
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		_, err := w.Write([]byte(`{"user":{"id":"user1","name":"Test User"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	var requests []DryRunRequest
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.DryRun = true
+	config.OnDryRun = func(ctx context.Context, req DryRunRequest) {
+		requests = append(requests, req)
+	}
+	client := NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	// Reads still go through
+	user, err := client.GetUser(context.Background(), token, "org1", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test User", user.Name)
+
+	// Mutations are skipped and synthesized
+	user, err = client.UpdateUser(context.Background(), token, "org1", "user1", UpdateUserParameter{UserName: "Renamed User"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", user.ID)
+	assert.Equal(t, "Renamed User", user.Name)
+
+	params := CreateKeyParameter{ScheduleType: "temporary", Role: "user"}
+	params.TemporarySchedule.StartDateTime = "2024-01-01T09:00:00+09:00"
+	key, err := client.CreateKey(context.Background(), token, "org1", "user1", "A1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, DryRunID, key.ID)
+	assert.Equal(t, "A1", key.Akerun.ID)
+	assert.Equal(t, "2024-01-01T09:00:00+09:00", key.TemporarySchedule.StartDateTime)
+
+	params = CreateKeyParameter{ScheduleType: "recurring", Role: "user", EnableKeyUrl: true, KeyUrlPassword: "secret"}
+	params.RecurringSchedule.DaysOfWeek = []uint32{1, 3}
+	params.RecurringSchedule.StartTime = "09:00"
+	params.RecurringSchedule.EndTime = "18:00"
+	key, err = client.CreateKey(context.Background(), token, "org1", "user1", "A1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, "recurring", key.ScheduleType)
+	assert.Equal(t, []uint32{1, 3}, key.RecurringSchedule.DaysOfWeek)
+	assert.Equal(t, "09:00", key.RecurringSchedule.StartTime)
+	assert.Equal(t, "18:00", key.RecurringSchedule.EndTime)
+	assert.Equal(t, DryRunID, key.Keys.KeyUrl)
+	assert.True(t, key.Keys.PasswordProtected)
+	assert.Equal(t, "user1", key.User.ID)
+
+	err = client.DeleteKey(context.Background(), token, "org1", "key1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{http.MethodGet}, methods)
+	assert.Len(t, requests, 4)
+	assert.Equal(t, OpUpdateUser, requests[0].Operation)
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, ts.URL+"/v3/organizations/org1/users/user1", requests[0].URL)
+	assert.Equal(t, "user_id=user1&user_name=Renamed+User", string(requests[0].Body))
+	assert.Equal(t, http.MethodDelete, requests[3].Method)
+}
@@ -0,0 +1,210 @@
+package akerun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoding represents how the parameters of an operation are sent.
+type Encoding int
+
+// Encodings
+const (
+	// EncodingQuery sends the parameters in the query string and no body.
+	EncodingQuery Encoding = iota
+	// EncodingForm sends the parameters as an application/x-www-form-urlencoded body.
+	EncodingForm
+	// EncodingJSON sends the parameters as a JSON body. Bracketed names such as
+	// "temporary_schedule[start_time]" and "akerun_ids[]" become nested objects and arrays,
+	// and values keep the types of the fields of the parameter struct, such as booleans and arrays of numbers.
+	EncodingJSON
+)
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	switch e {
+	case EncodingQuery:
+		return "query"
+	case EncodingForm:
+		return "form"
+	case EncodingJSON:
+		return "json"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// defaultEncodings are the encodings of the operations that send their parameters in the body.
+// All other operations use EncodingQuery.
+var defaultEncodings = map[OperationName]Encoding{
+	OpCreateAkerunGroup: EncodingForm,
+	OpUpdateAkerunGroup: EncodingForm,
+	OpAddAkerunToGroup:  EncodingForm,
+	OpCreateKey:         EncodingForm,
+	OpUpdateKey:         EncodingForm,
+	OpRegisterUser:      EncodingForm,
+	OpInviteUser:        EncodingForm,
+	OpUpdateUser:        EncodingForm,
+	OpRevoke:            EncodingForm,
+}
+
+// bodyParameters are the parameter structs of the operations that send a body.
+// The JSON encoding takes the types of the values from their fields.
+var bodyParameters = map[OperationName]interface{}{
+	OpCreateAkerunGroup: AkerunGroupCreateParameter{},
+	OpUpdateAkerunGroup: AkerunGroupUpdateParameter{},
+	OpCreateKey:         CreateKeyParameter{},
+	OpUpdateKey:         UpdateKeyParameter{},
+	OpRegisterUser:      RegisterUserParameter{},
+	OpInviteUser:        InviteUserParameter{},
+	OpUpdateUser:        UpdateUserParameter{},
+}
+
+// encoding returns the encoding of the operation, taking Config.Encodings into account.
+func (c *Client) encoding(name OperationName) Encoding {
+	if e, ok := c.config.Encodings[name]; ok {
+		return e
+	}
+	return defaultEncodings[name]
+}
+
+// encodeBody encodes the body of an operation and returns it with its content type.
+// It returns a nil reader if the operation has no body.
+func encodeBody(op *Operation) (io.Reader, string, error) {
+	if op.Body == nil {
+		return nil, "", nil
+	}
+
+	switch op.Encoding {
+	case EncodingForm:
+		values, ok := op.Body.(url.Values)
+		if !ok {
+			return nil, "", fmt.Errorf("akerun: form body of %s must be url.Values, got %T", op.Name, op.Body)
+		}
+		return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+	case EncodingJSON:
+		body := op.Body
+		if values, ok := body.(url.Values); ok {
+			body = valuesToJSON(values, parameterTypes(bodyParameters[op.Name]))
+		}
+		byt, err := json.Marshal(body)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(byt), "application/json", nil
+	default:
+		return nil, "", fmt.Errorf("akerun: %s has a body but uses %v encoding", op.Name, op.Encoding)
+	}
+}
+
+// valuesToJSON converts parameters in bracket notation to a JSON object.
+// "a[b]" becomes {"a":{"b":...}}, while "a[]", repeated names and slice fields become arrays.
+// Values of bool and number fields in types keep their type, and all other values are strings.
+func valuesToJSON(values url.Values, types map[string]reflect.Type) map[string]interface{} {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	root := map[string]interface{}{}
+	for _, key := range keys {
+		name, rest, _ := strings.Cut(key, "[")
+		obj := root
+		for rest != "" && rest != "]" {
+			var child string
+			child, rest, _ = strings.Cut(rest, "]")
+			rest = strings.TrimPrefix(rest, "[")
+			next, ok := obj[name].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				obj[name] = next
+			}
+			obj, name = next, child
+		}
+
+		t, ok := types[key]
+		switch {
+		case ok && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			array := make([]interface{}, 0, len(values[key]))
+			for _, v := range values[key] {
+				array = append(array, jsonValue(v, t.Elem()))
+			}
+			obj[name] = array
+		case strings.HasSuffix(key, "[]") || len(values[key]) > 1:
+			obj[name] = append([]string{}, values[key]...)
+		case ok:
+			obj[name] = jsonValue(values.Get(key), t)
+		default:
+			obj[name] = values.Get(key)
+		}
+	}
+	return root
+}
+
+// jsonValue converts a parameter value to the JSON type of t. Values that do not parse stay strings.
+func jsonValue(s string, t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// parameterTypes returns the field types of a parameter struct by the names go-querystring encodes them under,
+// such as "recurring_schedule[days_of_week]". Slice fields are listed with and without the "[]" suffix.
+func parameterTypes(params interface{}) map[string]reflect.Type {
+	types := map[string]reflect.Type{}
+	if params != nil {
+		addParameterTypes(types, reflect.TypeOf(params), "")
+	}
+	return types
+}
+
+// addParameterTypes adds the fields of the struct type t, nested under prefix, to types.
+func addParameterTypes(types map[string]reflect.Type, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("url"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+
+		switch f.Type.Kind() {
+		case reflect.Struct:
+			addParameterTypes(types, f.Type, name)
+		case reflect.Slice, reflect.Array:
+			types[name] = f.Type
+			types[name+"[]"] = f.Type
+		default:
+			types[name] = f.Type
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package akerun
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// wireRequest represents a request as received by the server.
+type wireRequest struct {
+	Method      string
+	Path        string
+	Query       string
+	ContentType string
+	Body        string
+}
+
+func TestClient_WireFormat(t *testing.T) {
+	var got wireRequest
+	// Create a test server that records the requests
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = wireRequest{
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			Query:       r.URL.RawQuery,
+			ContentType: r.Header.Get("Content-Type"),
+			Body:        string(body),
+		}
+		_, err = w.Write([]byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	ctx := context.Background()
+	const form = "application/x-www-form-urlencoded"
+
+	keyParams := CreateKeyParameter{ScheduleType: ScheduleTypeRecurring}
+	keyParams.RecurringSchedule.DaysOfWeek = []uint32{1, 2}
+	keyParams.RecurringSchedule.StartTime = "09:00"
+
+	tests := []struct {
+		name string
+		call func() error
+		want wireRequest
+	}{
+		{
+			"GetOrganizations",
+			func() error {
+				_, err := client.GetOrganizations(ctx, token, OrganizationsParameter{Limit: 10})
+				return err
+			},
+			wireRequest{http.MethodGet, "/v3/organizations", "limit=10", "", ""},
+		},
+		{
+			"GetOrganization",
+			func() error { _, err := client.GetOrganization(ctx, token, "O1"); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1", "", "", ""},
+		},
+		{
+			"GetAkeruns",
+			func() error {
+				_, err := client.GetAkeruns(ctx, token, "O1", AkerunListParameter{Limit: 10})
+				return err
+			},
+			wireRequest{http.MethodGet, "/v3/organizations/O1/akeruns", "limit=10", "", ""},
+		},
+		{
+			"GetAkerunGroups",
+			func() error { _, err := client.GetAkerunGroups(ctx, token, "O1"); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/akerun_groups", "", "", ""},
+		},
+		{
+			"GetAkerunGroup",
+			func() error { _, err := client.GetAkerunGroup(ctx, token, "O1", "G1"); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/akerun_groups/G1", "", "", ""},
+		},
+		{
+			"CreateAkerunGroup",
+			func() error {
+				_, err := client.CreateAkerunGroup(ctx, token, "O1", AkerunGroupCreateParameter{Name: "Front", Memo: "1F"})
+				return err
+			},
+			wireRequest{http.MethodPost, "/v3/organizations/O1/akerun_groups", "", form, "memo=1F&name=Front"},
+		},
+		{
+			"UpdateAkerunGroup",
+			func() error {
+				_, err := client.UpdateAkerunGroup(ctx, token, "O1", "G1", AkerunGroupUpdateParameter{Name: "Back"})
+				return err
+			},
+			wireRequest{http.MethodPut, "/v3/organizations/O1/akerun_groups/G1", "", form, "memo=&name=Back"},
+		},
+		{
+			"DeleteAkerunGroup",
+			func() error { return client.DeleteAkerunGroup(ctx, token, "O1", "G1") },
+			wireRequest{http.MethodDelete, "/v3/organizations/O1/akerun_groups/G1", "", "", ""},
+		},
+		{
+			"AddAkerunToGroup",
+			func() error { return client.AddAkerunToGroup(ctx, token, "O1", "G1", "A1", "A2") },
+			wireRequest{http.MethodPost, "/v3/organizations/O1/akerun_groups/G1/akeruns", "", form, "akerun_ids%5B%5D=A1&akerun_ids%5B%5D=A2"},
+		},
+		{
+			"RemoveAkerunFromGroup",
+			func() error { return client.RemoveAkerunFromGroup(ctx, token, "O1", "G1", "A1") },
+			wireRequest{http.MethodDelete, "/v3/organizations/O1/akerun_groups/G1/akeruns", "akerun_ids%5B%5D=A1", "", ""},
+		},
+		{
+			"GetKeys",
+			func() error { _, err := client.GetKeys(ctx, token, "O1", KeysParameter{UserId: "U1"}); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/keys", "user_id=U1", "", ""},
+		},
+		{
+			"GetKey",
+			func() error { _, err := client.GetKey(ctx, token, "O1", KeyParameter{KeyId: "K1"}); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/keys", "key_id=K1", "", ""},
+		},
+		{
+			"CreateKey",
+			func() error { _, err := client.CreateKey(ctx, token, "O1", "U1", "A1", keyParams); return err },
+			wireRequest{
+				http.MethodPost, "/v3/organizations/O1/keys", "", form,
+				"akerun_id=A1&recurring_schedule%5Bdays_of_week%5D=1&recurring_schedule%5Bdays_of_week%5D=2&recurring_schedule%5Bstart_time%5D=09%3A00&schedule_type=recurring&user_id=U1",
+			},
+		},
+		{
+			"UpdateKey",
+			func() error {
+				_, err := client.UpdateKey(ctx, token, "O1", "K1", ScheduleTypePermanent, UpdateKeyParameter{Role: "manager"})
+				return err
+			},
+			wireRequest{http.MethodPut, "/v3/organizations/O1/keys/K1", "", form, "role=manager&schedule_type=permanent"},
+		},
+		{
+			"DeleteKey",
+			func() error { return client.DeleteKey(ctx, token, "O1", "K1") },
+			wireRequest{http.MethodDelete, "/v3/organizations/O1/keys/K1", "", "", ""},
+		},
+		{
+			"GetUsers",
+			func() error { _, err := client.GetUsers(ctx, token, "O1", UsersParameter{UserCode: "C1"}); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/users", "user_code=C1", "", ""},
+		},
+		{
+			"GetUser",
+			func() error { _, err := client.GetUser(ctx, token, "O1", "U1"); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/users/U1", "", "", ""},
+		},
+		{
+			"RegisterUser",
+			func() error {
+				_, err := client.RegisterUser(ctx, token, "O1", "Alice", RegisterUserParameter{UserMail: "alice@example.com"})
+				return err
+			},
+			wireRequest{http.MethodPost, "/v3/organizations/O1/users", "", form, "user_mail=alice%40example.com&user_name=Alice"},
+		},
+		{
+			"InviteUser",
+			func() error {
+				_, err := client.InviteUser(ctx, token, "O1", "U1", InviteUserParameter{UserCode: "C1"})
+				return err
+			},
+			wireRequest{http.MethodPost, "/v3/organizations/O1/users/U1", "", form, "user_code=C1&user_id=U1"},
+		},
+		{
+			"UpdateUser",
+			func() error {
+				_, err := client.UpdateUser(ctx, token, "O1", "U1", UpdateUserParameter{UserName: "Bob"})
+				return err
+			},
+			wireRequest{http.MethodPut, "/v3/organizations/O1/users/U1", "", form, "user_id=U1&user_name=Bob"},
+		},
+		{
+			"ExitUser",
+			func() error { return client.ExitUser(ctx, token, "O1", "U1") },
+			wireRequest{http.MethodDelete, "/v3/organizations/O1/users/U1", "", "", ""},
+		},
+		{
+			"GetAccesses",
+			func() error { _, err := client.GetAccesses(ctx, token, "O1", AccessesParameter{Limit: 10}); return err },
+			wireRequest{http.MethodGet, "/v3/organizations/O1/accesses", "limit=10", "", ""},
+		},
+		{
+			"Revoke",
+			func() error { return client.Revoke(ctx, token) },
+			wireRequest{http.MethodPost, "/oauth/revoke", "", form, "client_id=testId&client_secret=testPass&token=test_token&token_type_hint=access_token"},
+		},
+		{
+			"GetTokenInfo",
+			func() error { _, err := client.GetTokenInfo(ctx, token); return err },
+			wireRequest{http.MethodGet, "/oauth/token/info", "", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = wireRequest{}
+			assert.NoError(t, tt.call())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_WireFormat_JSON(t *testing.T) {
+	var body map[string]interface{}
+	// Create a test server that decodes the JSON body
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Empty(t, r.URL.RawQuery)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, err := w.Write([]byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Encodings = map[OperationName]Encoding{OpCreateKey: EncodingJSON}
+	client := NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	params := CreateKeyParameter{ScheduleType: ScheduleTypeRecurring}
+	params.RecurringSchedule.DaysOfWeek = []uint32{1, 2}
+	params.RecurringSchedule.StartTime = "09:00"
+	_, err := client.CreateKey(context.Background(), token, "O1", "U1", "A1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"akerun_id":     "A1",
+		"user_id":       "U1",
+		"schedule_type": "recurring",
+		"recurring_schedule": map[string]interface{}{
+			"days_of_week": []interface{}{float64(1), float64(2)},
+			"start_time":   "09:00",
+		},
+	}, body)
+}
+
+func TestValuesToJSON(t *testing.T) {
+	values := url.Values{
+		"name":                     {"Front"},
+		"akerun_ids[]":             {"A1"},
+		"temporary_schedule[a][b]": {"c"},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"name":               "Front",
+		"akerun_ids":         []string{"A1"},
+		"temporary_schedule": map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+	}, valuesToJSON(values, nil))
+}
+
+func TestClient_WireFormat_JSONTypes(t *testing.T) {
+	var body map[string]interface{}
+	// Create a test server that decodes the JSON body
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, err := w.Write([]byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Encodings = map[OperationName]Encoding{OpCreateKey: EncodingJSON, OpUpdateKey: EncodingJSON}
+	client := NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	// A single day is still an array, and booleans stay booleans
+	params := CreateKeyParameter{ScheduleType: ScheduleTypeRecurring, EnableKeyUrl: true}
+	params.RecurringSchedule.DaysOfWeek = []uint32{3}
+	_, err := client.CreateKey(context.Background(), token, "O1", "U1", "A1", params)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"akerun_id":      "A1",
+		"user_id":        "U1",
+		"schedule_type":  "recurring",
+		"enable_key_url": true,
+		"recurring_schedule": map[string]interface{}{
+			"days_of_week": []interface{}{float64(3)},
+		},
+	}, body)
+
+	update := UpdateKeyParameter{EnableKeyUrl: true, KeyUrlPassword: "1234"}
+	update.RecurringSchedule.DaysOfWeek = []uint32{0}
+	update.RecurringSchedule.EndTime = "18:00"
+	_, err = client.UpdateKey(context.Background(), token, "O1", "K1", ScheduleTypeRecurring, update)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"schedule_type":    "recurring",
+		"enable_key_url":   true,
+		"key_url_password": "1234",
+		"recurring_schedule": map[string]interface{}{
+			"days_of_week": []interface{}{float64(0)},
+			"end_time":     "18:00",
+		},
+	}, body)
+}
+
+func TestParameterTypes(t *testing.T) {
+	types := parameterTypes(CreateKeyParameter{})
+	assert.Equal(t, reflect.TypeOf([]uint32{}), types["recurring_schedule[days_of_week]"])
+	assert.Equal(t, reflect.TypeOf(""), types["temporary_schedule[start_datetime]"])
+	assert.Equal(t, reflect.TypeOf(true), types["enable_key_url"])
+}
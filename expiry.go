@@ -0,0 +1,167 @@
+package akerun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Hayao0819/go-akerun/internal/pager"
+	"golang.org/x/oauth2"
+)
+
+// ExpiringKey represents a temporary key that ends within a scanned window.
+type ExpiringKey struct {
+	Key    Key
+	EndsAt time.Time
+}
+
+// ExpiryScan represents the temporary keys ending between From and To, sorted by end time.
+type ExpiryScan struct {
+	From time.Time
+	To   time.Time
+	Keys []ExpiringKey
+}
+
+// ByUser groups the expiring keys by user ID.
+func (s *ExpiryScan) ByUser() map[string][]ExpiringKey {
+	groups := map[string][]ExpiringKey{}
+	for _, k := range s.Keys {
+		groups[k.Key.User.ID] = append(groups[k.Key.User.ID], k)
+	}
+	return groups
+}
+
+// ByAkerun groups the expiring keys by Akerun ID.
+func (s *ExpiryScan) ByAkerun() map[string][]ExpiringKey {
+	groups := map[string][]ExpiringKey{}
+	for _, k := range s.Keys {
+		groups[k.Key.Akerun.ID] = append(groups[k.Key.Akerun.ID], k)
+	}
+	return groups
+}
+
+// ScanExpiringKeys returns the temporary keys of an organization that end between now and now+within.
+// Keys whose end time cannot be parsed are ignored.
+func (c *Client) ScanExpiringKeys(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	now time.Time,
+	within time.Duration,
+) (*ExpiryScan, error) {
+	scan := &ExpiryScan{From: now, To: now.Add(within)}
+
+	keys, err := pager.All(func(idAfter string) ([]Key, error) {
+		result, err := c.GetKeys(ctx, oauth2Token, organizationId, KeysParameter{Limit: pager.Limit, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		return result.Keys, nil
+	}, func(k Key) string { return k.ID })
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.ScheduleType != ScheduleTypeTemporary {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, k.TemporarySchedule.EndDateTime)
+		if err != nil || end.Before(scan.From) || end.After(scan.To) {
+			continue
+		}
+		scan.Keys = append(scan.Keys, ExpiringKey{Key: k, EndsAt: end})
+	}
+
+	sort.SliceStable(scan.Keys, func(i, j int) bool {
+		return scan.Keys[i].EndsAt.Before(scan.Keys[j].EndsAt)
+	})
+	return scan, nil
+}
+
+// RenewalPolicy represents how RenewExpiringKeys renews keys.
+type RenewalPolicy struct {
+	// ExtendDays is the number of days the end of each key is moved by. It must be positive.
+	ExtendDays int
+	// SkipExitedUsers skips the keys of users who are no longer in the organization.
+	SkipExitedUsers bool
+	// Filter selects the keys to renew. If nil, every key is renewed.
+	Filter func(ExpiringKey) bool
+}
+
+// Renewal skip reasons
+const (
+	RenewalSkippedByFilter = "filtered"
+	RenewalSkippedExited   = "user exited"
+)
+
+// RenewalResult represents the outcome of renewing a key.
+type RenewalResult struct {
+	Key ExpiringKey
+	// Renewed is the updated key, or nil if the key was skipped or the update failed.
+	Renewed *Key
+	// Skipped is the reason the key was not renewed, or empty.
+	Skipped string
+	Err     error
+}
+
+// RenewExpiringKeys extends the keys of scan according to policy.
+// Failures are reported per key; the returned error is only set if ctx is canceled
+// or policy.ExtendDays is not positive, in which case no key is updated.
+func (c *Client) RenewExpiringKeys(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	scan *ExpiryScan,
+	policy RenewalPolicy,
+) ([]RenewalResult, error) {
+	if policy.ExtendDays <= 0 {
+		return nil, fmt.Errorf("akerun: ExtendDays must be positive, got %d", policy.ExtendDays)
+	}
+
+	present := map[string]error{}
+	userPresent := func(userId string) (bool, error) {
+		err, ok := present[userId]
+		if !ok {
+			_, err = c.GetUser(ctx, oauth2Token, organizationId, userId)
+			present[userId] = err
+		}
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	results := make([]RenewalResult, 0, len(scan.Keys))
+	for _, k := range scan.Keys {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := RenewalResult{Key: k}
+		switch {
+		case policy.Filter != nil && !policy.Filter(k):
+			result.Skipped = RenewalSkippedByFilter
+		case policy.SkipExitedUsers:
+			ok, err := userPresent(k.Key.User.ID)
+			if err != nil {
+				result.Err = err
+			} else if !ok {
+				result.Skipped = RenewalSkippedExited
+			}
+		}
+
+		if result.Skipped == "" && result.Err == nil {
+			var params UpdateKeyParameter
+			params.TemporarySchedule.StartDateTime = k.Key.TemporarySchedule.StartDateTime
+			params.TemporarySchedule.EndDateTime = k.EndsAt.AddDate(0, 0, policy.ExtendDays).Format(time.RFC3339)
+			params.Role = k.Key.Role
+			result.Renewed, result.Err = c.UpdateKey(ctx, oauth2Token, organizationId, k.Key.ID, ScheduleTypeTemporary, params)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,79 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_ScanAndRenewExpiringKeys(t *testing.T) {
+	var updated []string
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/organizations/org1/keys":
+			body = `{"keys":[
+				{"id":"key1","schedule_type":"temporary","temporary_schedule":{"start_datetime":"2024-01-01T09:00:00+09:00","end_datetime":"2024-01-08T09:00:00+09:00"},"akerun":{"id":"A1"},"user":{"id":"user1"}},
+				{"id":"key2","schedule_type":"temporary","temporary_schedule":{"start_datetime":"2024-01-01T09:00:00+09:00","end_datetime":"2024-01-07T18:00:00+09:00"},"akerun":{"id":"A2"},"user":{"id":"user2"}},
+				{"id":"key3","schedule_type":"temporary","temporary_schedule":{"start_datetime":"2024-01-01T09:00:00+09:00","end_datetime":"2024-02-01T09:00:00+09:00"},"akerun":{"id":"A1"},"user":{"id":"user1"}},
+				{"id":"key4","schedule_type":"permanent","akerun":{"id":"A1"},"user":{"id":"user1"}}
+			]}`
+		case r.URL.Path == "/v3/organizations/org1/users/user1":
+			body = `{"user":{"id":"user1"}}`
+		case r.URL.Path == "/v3/organizations/org1/users/user2":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case r.Method == http.MethodPut:
+			updated = append(updated, r.URL.Path+" "+r.PostFormValue("temporary_schedule[end_datetime]"))
+			body = `{"key":{"id":"key1"}}`
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	client := NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+	now := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	scan, err := client.ScanExpiringKeys(context.Background(), token, "org1", now, 7*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, scan.Keys, 2)
+	assert.Equal(t, "key2", scan.Keys[0].Key.ID)
+	assert.Equal(t, "key1", scan.Keys[1].Key.ID)
+	assert.Len(t, scan.ByUser()["user1"], 1)
+	assert.Len(t, scan.ByAkerun()["A2"], 1)
+
+	results, err := client.RenewExpiringKeys(context.Background(), token, "org1", scan, RenewalPolicy{ExtendDays: 7, SkipExitedUsers: true})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, RenewalSkippedExited, results[0].Skipped)
+	assert.NoError(t, results[1].Err)
+	assert.NotNil(t, results[1].Renewed)
+	assert.Equal(t, []string{"/v3/organizations/org1/keys/key1 2024-01-15T09:00:00+09:00"}, updated)
+
+	// Keys are never shortened or left as they are
+	updated = nil
+	for _, days := range []int{0, -1} {
+		results, err = client.RenewExpiringKeys(context.Background(), token, "org1", scan, RenewalPolicy{ExtendDays: days})
+		assert.Error(t, err)
+		assert.Empty(t, results)
+	}
+	assert.Empty(t, updated)
+}
@@ -0,0 +1,242 @@
+package akerun
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Extra holds the fields of an API object that the model does not know about,
+// so that fields added to the API are not lost when objects are decoded and encoded again.
+// Every model type and nested type with a name, such as NFC, keeps its unknown fields.
+// Objects nested in a model without a type of their own, such as Key.User, only keep their known fields.
+type Extra map[string]json.RawMessage
+
+// UnmarshalJSON decodes the organization and keeps unknown fields in Extra.
+func (o *Organization) UnmarshalJSON(data []byte) error {
+	type plain Organization
+	return unmarshalExtra(data, (*plain)(o), &o.Extra)
+}
+
+// MarshalJSON encodes the organization including the fields in Extra.
+func (o Organization) MarshalJSON() ([]byte, error) {
+	type plain Organization
+	return marshalExtra(plain(o), o.Extra)
+}
+
+// UnmarshalJSON decodes the Akerun, keeps unknown fields in Extra and records unknown battery levels.
+func (a *Akerun) UnmarshalJSON(data []byte) error {
+	type plain Akerun
+	if err := unmarshalExtra(data, (*plain)(a), &a.Extra); err != nil {
+		return err
+	}
+	unknown, err := unknownBatteries(data)
+	if err != nil {
+		return err
+	}
+	a.UnknownBatteries = unknown
+	return nil
+}
+
+// MarshalJSON encodes the Akerun including the fields in Extra, and encodes unknown battery levels as null.
+func (a Akerun) MarshalJSON() ([]byte, error) {
+	type plain Akerun
+	byt, err := marshalExtra(plain(a), a.Extra)
+	if err != nil || a.UnknownBatteries == (UnknownBatteries{}) {
+		return byt, err
+	}
+	return a.UnknownBatteries.nullify(byt)
+}
+
+// UnmarshalJSON decodes the Akerun group and keeps unknown fields in Extra.
+func (g *AkerunGroup) UnmarshalJSON(data []byte) error {
+	type plain AkerunGroup
+	return unmarshalExtra(data, (*plain)(g), &g.Extra)
+}
+
+// MarshalJSON encodes the Akerun group including the fields in Extra.
+func (g AkerunGroup) MarshalJSON() ([]byte, error) {
+	type plain AkerunGroup
+	return marshalExtra(plain(g), g.Extra)
+}
+
+// UnmarshalJSON decodes the Akerun group and keeps unknown fields in Extra.
+func (g *AkerunGroupDetailed) UnmarshalJSON(data []byte) error {
+	type plain AkerunGroupDetailed
+	return unmarshalExtra(data, (*plain)(g), &g.Extra)
+}
+
+// MarshalJSON encodes the Akerun group including the fields in Extra.
+func (g AkerunGroupDetailed) MarshalJSON() ([]byte, error) {
+	type plain AkerunGroupDetailed
+	return marshalExtra(plain(g), g.Extra)
+}
+
+// UnmarshalJSON decodes the key and keeps unknown fields in Extra.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	type plain Key
+	return unmarshalExtra(data, (*plain)(k), &k.Extra)
+}
+
+// MarshalJSON encodes the key including the fields in Extra.
+func (k Key) MarshalJSON() ([]byte, error) {
+	type plain Key
+	return marshalExtra(plain(k), k.Extra)
+}
+
+// UnmarshalJSON decodes the user and keeps unknown fields in Extra.
+func (u *User) UnmarshalJSON(data []byte) error {
+	type plain User
+	return unmarshalExtra(data, (*plain)(u), &u.Extra)
+}
+
+// MarshalJSON encodes the user including the fields in Extra.
+func (u User) MarshalJSON() ([]byte, error) {
+	type plain User
+	return marshalExtra(plain(u), u.Extra)
+}
+
+// UnmarshalJSON decodes the NFC card and keeps unknown fields in Extra.
+func (n *NFC) UnmarshalJSON(data []byte) error {
+	type plain NFC
+	return unmarshalExtra(data, (*plain)(n), &n.Extra)
+}
+
+// MarshalJSON encodes the NFC card including the fields in Extra.
+func (n NFC) MarshalJSON() ([]byte, error) {
+	type plain NFC
+	return marshalExtra(plain(n), n.Extra)
+}
+
+// UnmarshalJSON decodes the access and keeps unknown fields in Extra.
+func (a *Access) UnmarshalJSON(data []byte) error {
+	type plain Access
+	return unmarshalExtra(data, (*plain)(a), &a.Extra)
+}
+
+// MarshalJSON encodes the access including the fields in Extra.
+func (a Access) MarshalJSON() ([]byte, error) {
+	type plain Access
+	return marshalExtra(plain(a), a.Extra)
+}
+
+// UnmarshalJSON decodes the token information and keeps unknown fields in Extra.
+func (i *TokenInfo) UnmarshalJSON(data []byte) error {
+	type plain TokenInfo
+	return unmarshalExtra(data, (*plain)(i), &i.Extra)
+}
+
+// MarshalJSON encodes the token information including the fields in Extra.
+func (i TokenInfo) MarshalJSON() ([]byte, error) {
+	type plain TokenInfo
+	return marshalExtra(plain(i), i.Extra)
+}
+
+// unmarshalExtra decodes data into v, a pointer to a struct, and stores the fields v has no field for in extra.
+func unmarshalExtra(data []byte, v interface{}, extra *Extra) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	*extra = nil
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Not an object, e.g. null, which leaves v untouched.
+		return nil
+	}
+	known := jsonFields(reflect.TypeOf(v).Elem())
+	for name, value := range fields {
+		if _, ok := known.lookup(name); ok {
+			continue
+		}
+		if *extra == nil {
+			*extra = Extra{}
+		}
+		(*extra)[name] = value
+	}
+	return nil
+}
+
+// marshalExtra encodes v, a struct, and adds the fields in extra that v does not have.
+func marshalExtra(v interface{}, extra Extra) ([]byte, error) {
+	byt, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return byt, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(byt, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// jsonField represents a struct field as seen by encoding/json.
+type jsonField struct {
+	name      string
+	omitempty bool
+	typ       reflect.Type
+}
+
+// jsonFieldSet represents the JSON fields of a struct type.
+type jsonFieldSet []jsonField
+
+// lookup returns the field for a JSON object key. Like encoding/json, an exact match
+// is preferred over a case-insensitive one.
+func (s jsonFieldSet) lookup(name string) (jsonField, bool) {
+	for _, f := range s {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range s {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// jsonFieldsCache caches jsonFields by type.
+var jsonFieldsCache sync.Map
+
+// jsonFields returns the JSON fields of a struct type, including those of embedded structs.
+func jsonFields(t reflect.Type) jsonFieldSet {
+	if cached, ok := jsonFieldsCache.Load(t); ok {
+		return cached.(jsonFieldSet)
+	}
+
+	var fields jsonFieldSet
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{
+			name:      name,
+			omitempty: strings.Contains(","+opts+",", ",omitempty,"),
+			typ:       f.Type,
+		})
+	}
+
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
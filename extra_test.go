@@ -0,0 +1,35 @@
+package akerun
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_Extra(t *testing.T) {
+	data := []byte(`{"id":"user1","name":"Test User","nickname":"tester","tags":["a","b"],"nfcs":[{"id":"NFC1","name":"Card","type":"felica"}]}`)
+
+	var user User
+	assert.NoError(t, json.Unmarshal(data, &user))
+	assert.Equal(t, "user1", user.ID)
+	assert.Equal(t, Extra{
+		"nickname": json.RawMessage(`"tester"`),
+		"tags":     json.RawMessage(`["a","b"]`),
+	}, user.Extra)
+	// Nested models keep their unknown fields too
+	assert.Equal(t, Extra{"type": json.RawMessage(`"felica"`)}, user.Nfcs[0].Extra)
+
+	// Unknown fields survive a round trip
+	byt, err := json.Marshal(user)
+	assert.NoError(t, err)
+	var again User
+	assert.NoError(t, json.Unmarshal(byt, &again))
+	assert.Equal(t, user, again)
+
+	// Known fields are not kept in Extra
+	var key Key
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":"key1","user":{"id":"user1"}}`), &key))
+	assert.Nil(t, key.Extra)
+	assert.Equal(t, "user1", key.User.ID)
+}
@@ -0,0 +1,516 @@
+package akerun
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// iCalendar formats
+const (
+	icalDateTimeUTC = "20060102T150405Z"
+	icalDateTime    = "20060102T150405"
+	icalDate        = "20060102"
+	icalProdID      = "-//Hayao0819//go-akerun//EN"
+	icalLineLength  = 75
+)
+
+// icalWeekdays maps time.Weekday to the iCalendar weekday names.
+var icalWeekdays = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ExportICalendar writes keys as an iCalendar (RFC 5545) calendar.
+// Temporary keys become single events, and recurring keys become weekly recurring events
+// starting on the first matching day on or after since, in loc or in time.Local if loc is nil.
+// Permanent keys have no bounded schedule and are omitted.
+func ExportICalendar(w io.Writer, keys []Key, since time.Time, loc *time.Location) error {
+	loc = scheduleLocation(loc)
+	b := &icalWriter{w: bufio.NewWriter(w)}
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:" + icalProdID)
+
+	recurring := false
+	for _, k := range keys {
+		if k.ScheduleType == ScheduleTypeRecurring {
+			recurring = true
+		}
+	}
+	if recurring {
+		writeVTimezone(b, since, loc)
+	}
+
+	stamp := time.Now().UTC().Format(icalDateTimeUTC)
+	for _, k := range keys {
+		s, err := ParseSchedule(k)
+		if err != nil {
+			return err
+		}
+		if s.Type == ScheduleTypePermanent {
+			continue
+		}
+
+		b.line("BEGIN:VEVENT")
+		b.line("UID:" + icalEscape(k.ID) + "@akerun")
+		b.line("DTSTAMP:" + stamp)
+		b.line("SUMMARY:" + icalEscape(keySummary(k)))
+		switch s.Type {
+		case ScheduleTypeTemporary:
+			b.line("DTSTART:" + s.Start.UTC().Format(icalDateTimeUTC))
+			b.line("DTEND:" + s.End.UTC().Format(icalDateTimeUTC))
+		case ScheduleTypeRecurring:
+			windows := s.windows(since, 7, loc)
+			if len(windows) == 0 {
+				return fmt.Errorf("akerun: recurring key %s has no days of week", k.ID)
+			}
+			tzid := icalEscape(loc.String())
+			b.line("DTSTART;TZID=" + tzid + ":" + windows[0][0].In(loc).Format(icalDateTime))
+			b.line("DTEND;TZID=" + tzid + ":" + windows[0][1].In(loc).Format(icalDateTime))
+			var days []string
+			for d, ok := range s.Days {
+				if ok {
+					days = append(days, icalWeekdays[d])
+				}
+			}
+			b.line("RRULE:FREQ=WEEKLY;BYDAY=" + strings.Join(days, ","))
+		}
+		b.line("END:VEVENT")
+	}
+
+	b.line("END:VCALENDAR")
+	if b.err != nil {
+		return b.err
+	}
+	return b.w.Flush()
+}
+
+// writeVTimezone writes a VTIMEZONE component describing loc around t.
+// The offset changes in the years around t are described as yearly rules when they follow one,
+// and listed one by one otherwise.
+func writeVTimezone(b *icalWriter, t time.Time, loc *time.Location) {
+	b.line("BEGIN:VTIMEZONE")
+	b.line("TZID:" + icalEscape(loc.String()))
+
+	// Start a year early so that the observance in effect at t is described.
+	from := time.Date(t.Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(icalZoneYears, 0, 0)
+	transitions := zoneTransitions(loc, from, to)
+	if len(transitions) == 0 {
+		name, offset := t.In(loc).Zone()
+		b.line("BEGIN:STANDARD")
+		b.line("DTSTART:19700101T000000")
+		b.line("TZOFFSETFROM:" + icalOffset(offset))
+		b.line("TZOFFSETTO:" + icalOffset(offset))
+		b.line("TZNAME:" + icalEscape(name))
+		b.line("END:STANDARD")
+		b.line("END:VTIMEZONE")
+		return
+	}
+
+	// Group the transitions into observances with the same offsets and name.
+	type observance struct {
+		dst          bool
+		name         string
+		offsetFrom   int
+		offsetTo     int
+		onsets       []time.Time
+		rules        map[string]bool
+		onsetsByYear map[int]int
+	}
+	var observances []*observance
+	for _, tr := range transitions {
+		_, offsetFrom := tr.Add(-time.Second).In(loc).Zone()
+		name, offsetTo := tr.In(loc).Zone()
+		dst := tr.In(loc).IsDST()
+		var o *observance
+		for _, candidate := range observances {
+			if candidate.dst == dst && candidate.name == name && candidate.offsetFrom == offsetFrom && candidate.offsetTo == offsetTo {
+				o = candidate
+			}
+		}
+		if o == nil {
+			o = &observance{dst: dst, name: name, offsetFrom: offsetFrom, offsetTo: offsetTo, rules: map[string]bool{}, onsetsByYear: map[int]int{}}
+			observances = append(observances, o)
+		}
+		// Onsets are written in the local time that was in effect before the transition.
+		onset := tr.In(time.FixedZone("", offsetFrom))
+		o.onsets = append(o.onsets, onset)
+		o.rules[icalYearlyRule(onset)] = true
+		o.onsetsByYear[onset.Year()]++
+	}
+
+	for _, o := range observances {
+		component := "STANDARD"
+		if o.dst {
+			component = "DAYLIGHT"
+		}
+		b.line("BEGIN:" + component)
+		b.line("DTSTART:" + o.onsets[0].Format(icalDateTime))
+		// A rule is only written if it held once in every year that was looked at.
+		yearly := len(o.rules) == 1 && len(o.onsetsByYear) == icalZoneYears && len(o.onsets) == icalZoneYears
+		if yearly {
+			b.line("RRULE:" + icalYearlyRule(o.onsets[0]))
+		} else {
+			for _, onset := range o.onsets[1:] {
+				b.line("RDATE:" + onset.Format(icalDateTime))
+			}
+		}
+		b.line("TZOFFSETFROM:" + icalOffset(o.offsetFrom))
+		b.line("TZOFFSETTO:" + icalOffset(o.offsetTo))
+		b.line("TZNAME:" + icalEscape(o.name))
+		b.line("END:" + component)
+	}
+	b.line("END:VTIMEZONE")
+}
+
+// icalZoneYears is the number of years of offset changes that writeVTimezone looks at.
+const icalZoneYears = 4
+
+// zoneTransitions returns the instants in [from, to) at which the UTC offset of loc changes.
+func zoneTransitions(loc *time.Location, from, to time.Time) []time.Time {
+	var transitions []time.Time
+	_, offset := from.In(loc).Zone()
+	for t := from; t.Before(to); t = t.Add(24 * time.Hour) {
+		next := t.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset == offset {
+			continue
+		}
+		// Search the second at which the offset changes.
+		lo, hi := t.Unix(), next.Unix()
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if _, o := time.Unix(mid, 0).In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transitions = append(transitions, time.Unix(hi, 0))
+		offset = nextOffset
+	}
+	return transitions
+}
+
+// icalYearlyRule returns the yearly RRULE that repeats onset on the same weekday of the month,
+// such as "FREQ=YEARLY;BYMONTH=3;BYDAY=2SU" or "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU".
+func icalYearlyRule(onset time.Time) string {
+	nth := (onset.Day()-1)/7 + 1
+	if onset.AddDate(0, 0, 7).Month() != onset.Month() {
+		nth = -1
+	}
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", int(onset.Month()), nth, icalWeekdays[onset.Weekday()])
+}
+
+// icalOffset formats a UTC offset in seconds as "+hhmm".
+func icalOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// keySummary returns the summary of the event of a key.
+func keySummary(k Key) string {
+	switch {
+	case k.User.Name != "" && k.Akerun.Name != "":
+		return k.User.Name + " - " + k.Akerun.Name
+	case k.Akerun.Name != "":
+		return k.Akerun.Name
+	default:
+		return "Akerun key " + k.ID
+	}
+}
+
+// icalWriter writes folded content lines and keeps the first error.
+type icalWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folding it at 75 octets.
+func (b *icalWriter) line(s string) {
+	if b.err != nil {
+		return
+	}
+	// Continuation lines start with a space, which counts towards their length.
+	limit := icalLineLength
+	for len(s) > limit {
+		cut := limit
+		// Do not split a UTF-8 sequence.
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		_, b.err = b.w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = icalLineLength - 1
+	}
+	if b.err == nil {
+		_, b.err = b.w.WriteString(s + "\r\n")
+	}
+}
+
+// icalEscape escapes a TEXT value.
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icalUnescape unescapes a TEXT value.
+func icalUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// ImportedSchedule represents a key schedule read from an iCalendar event.
+type ImportedSchedule struct {
+	UID     string
+	Summary string
+	Params  CreateKeyParameter
+	// Err is the reason the event cannot be imported, or nil. Params is not usable if it is set.
+	Err error
+}
+
+// ImportICalendar reads the events of an iCalendar (RFC 5545) calendar as key schedules.
+// Single events become temporary schedules and weekly recurring events become recurring schedules.
+// Times without a known TZID are interpreted in loc.
+// Events that a key schedule cannot represent exactly, such as rules with BYMONTH, EXDATE or RDATE,
+// or recurring events that start after now, are returned with Err set; the other events are imported anyway.
+// The returned error is only set if the calendar cannot be read.
+func ImportICalendar(r io.Reader, now time.Time, loc *time.Location) ([]ImportedSchedule, error) {
+	lines, err := icalUnfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		schedules []ImportedSchedule
+		event     map[string]icalProperty
+		// nested counts the components such as VALARM that are open inside the event.
+		nested int
+	)
+	for _, l := range lines {
+		p := parseICalProperty(l)
+		switch {
+		case event != nil && p.name == "BEGIN":
+			nested++
+		case event != nil && p.name == "END" && nested > 0:
+			nested--
+		case p.name == "BEGIN" && p.value == "VEVENT":
+			event = map[string]icalProperty{}
+		case p.name == "END" && p.value == "VEVENT":
+			if event == nil {
+				return nil, fmt.Errorf("akerun: unexpected END:VEVENT")
+			}
+			s, err := icalEventSchedule(event, now, loc)
+			s.Err = err
+			schedules = append(schedules, s)
+			event = nil
+		case event != nil && nested == 0:
+			event[p.name] = p
+		}
+	}
+	return schedules, nil
+}
+
+// icalEventSchedule converts the properties of a VEVENT into a key schedule.
+// Recurring events that start after now are rejected.
+func icalEventSchedule(event map[string]icalProperty, now time.Time, loc *time.Location) (ImportedSchedule, error) {
+	s := ImportedSchedule{
+		UID:     icalUnescape(event["UID"].value),
+		Summary: icalUnescape(event["SUMMARY"].value),
+	}
+
+	// Recurrence rules are evaluated in the zone of DTSTART, and the times are converted to loc afterwards.
+	start, err := parseICalTime(event["DTSTART"], loc)
+	if err != nil {
+		return s, fmt.Errorf("akerun: invalid DTSTART of event %q: %w", s.UID, err)
+	}
+	var end time.Time
+	switch {
+	case event["DTEND"].name != "":
+		if end, err = parseICalTime(event["DTEND"], loc); err != nil {
+			return s, fmt.Errorf("akerun: invalid DTEND of event %q: %w", s.UID, err)
+		}
+	case event["DURATION"].name != "":
+		d, err := parseICalDuration(event["DURATION"].value)
+		if err != nil {
+			return s, fmt.Errorf("akerun: invalid DURATION of event %q: %w", s.UID, err)
+		}
+		end = start.Add(d)
+	default:
+		return s, fmt.Errorf("akerun: event %q has no end", s.UID)
+	}
+
+	// shift is the number of days the conversion to loc moves the start, which BYDAY has to follow.
+	shift := dayOffset(start, start.In(loc))
+	start, end = start.In(loc), end.In(loc)
+
+	// Recurring keys and temporary keys cover a single window or rule, so dates added to or removed from it cannot be kept.
+	for _, name := range []string{"RDATE", "EXDATE", "EXRULE"} {
+		if _, ok := event[name]; ok {
+			return s, fmt.Errorf("akerun: unsupported %s of event %q", name, s.UID)
+		}
+	}
+
+	rrule, ok := event["RRULE"]
+	if !ok {
+		s.Params.ScheduleType = ScheduleTypeTemporary
+		s.Params.TemporarySchedule.StartDateTime = start.Format(time.RFC3339)
+		s.Params.TemporarySchedule.EndDateTime = end.Format(time.RFC3339)
+		return s, nil
+	}
+
+	rule := map[string]string{}
+	for _, part := range strings.Split(rrule.value, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "FREQ", "INTERVAL", "UNTIL", "COUNT", "BYDAY", "WKST":
+			rule[k] = v
+		default:
+			// Other parts such as BYMONTH or BYSETPOS narrow the occurrences in ways a recurring key cannot.
+			return s, fmt.Errorf("akerun: unsupported RRULE part %q of event %q", k, s.UID)
+		}
+	}
+	if rule["FREQ"] != "WEEKLY" && rule["FREQ"] != "DAILY" {
+		return s, fmt.Errorf("akerun: unsupported RRULE %q of event %q", rrule.value, s.UID)
+	}
+	// Recurring keys repeat every week without an end, so rules that skip weeks or end cannot be kept.
+	if interval, ok := rule["INTERVAL"]; ok && interval != "1" {
+		return s, fmt.Errorf("akerun: unsupported INTERVAL %q of event %q", interval, s.UID)
+	}
+	if _, ok := rule["UNTIL"]; ok {
+		return s, fmt.Errorf("akerun: unsupported UNTIL of event %q", s.UID)
+	}
+	if _, ok := rule["COUNT"]; ok {
+		return s, fmt.Errorf("akerun: unsupported COUNT of event %q", s.UID)
+	}
+	// Recurring keys take effect immediately, so a rule that starts later cannot be kept.
+	if start.After(now) {
+		return s, fmt.Errorf("akerun: recurring event %q starts in the future", s.UID)
+	}
+	if end.Sub(start) > 24*time.Hour {
+		return s, fmt.Errorf("akerun: recurring event %q lasts longer than a day", s.UID)
+	}
+
+	s.Params.ScheduleType = ScheduleTypeRecurring
+	s.Params.RecurringSchedule.StartTime = start.Format("15:04")
+	s.Params.RecurringSchedule.EndTime = end.Format("15:04")
+	switch {
+	case rule["BYDAY"] == "" && rule["FREQ"] == "DAILY":
+		s.Params.RecurringSchedule.DaysOfWeek = []uint32{0, 1, 2, 3, 4, 5, 6}
+	case rule["BYDAY"] == "":
+		s.Params.RecurringSchedule.DaysOfWeek = []uint32{uint32(start.Weekday())}
+	default:
+		for _, day := range strings.Split(rule["BYDAY"], ",") {
+			found := false
+			for d, name := range icalWeekdays {
+				if day == name {
+					s.Params.RecurringSchedule.DaysOfWeek = append(s.Params.RecurringSchedule.DaysOfWeek, uint32((d+shift+7)%7))
+					found = true
+				}
+			}
+			if !found {
+				return s, fmt.Errorf("akerun: unsupported BYDAY %q of event %q", day, s.UID)
+			}
+		}
+	}
+	return s, nil
+}
+
+// icalProperty represents a content line.
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICalProperty parses a content line such as "DTSTART;TZID=Asia/Tokyo:20240101T090000".
+func parseICalProperty(line string) icalProperty {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	p := icalProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return p
+}
+
+// parseICalTime parses a DATE or DATE-TIME property in its own zone.
+// UTC times stay in UTC, times with a known TZID are in that zone, and other times are in loc.
+func parseICalTime(p icalProperty, loc *time.Location) (time.Time, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(icalDate) {
+		return time.ParseInLocation(icalDate, p.value, loc)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse(icalDateTimeUTC, p.value)
+	}
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			return time.ParseInLocation(icalDateTime, p.value, l)
+		}
+	}
+	return time.ParseInLocation(icalDateTime, p.value, loc)
+}
+
+// dayOffset returns the number of calendar days from the date of a to the date of b.
+func dayOffset(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	d := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC).Sub(time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC))
+	return int(d / (24 * time.Hour))
+}
+
+// parseICalDuration parses a DURATION value such as "PT9H" or "P1DT2H30M".
+func parseICalDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	inTime := false
+	n := 0
+	for _, c := range rest {
+		switch {
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+			continue
+		case c == 'T':
+			inTime = true
+		case c == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n = 0
+	}
+	return d, nil
+}
+
+// icalUnfold reads the content lines of r, joining folded lines.
+func icalUnfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, scanner.Err()
+}
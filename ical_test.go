@@ -0,0 +1,184 @@
+package akerun
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICalendar_RoundTrip(t *testing.T) {
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	temporary := temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-03T18:00:00+09:00")
+	temporary.ID = "key1"
+	temporary.Akerun.Name = "Entrance; Main, 1F"
+	recurring := recurringKey("22:00", "06:00", 1, 3, 5)
+	recurring.ID = "key2"
+
+	var buf bytes.Buffer
+	err := ExportICalendar(&buf, []Key{temporary, recurring, {ID: "key3", ScheduleType: ScheduleTypePermanent}}, time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo), tokyo)
+	assert.NoError(t, err)
+
+	ics := buf.String()
+	assert.Contains(t, ics, "DTSTART:20240101T000000Z\r\n")
+	assert.Contains(t, ics, "SUMMARY:Entrance\\; Main\\, 1F\r\n")
+	assert.Contains(t, ics, "DTSTART;TZID=Asia/Tokyo:20240101T220000\r\n")
+	assert.Contains(t, ics, "DTEND;TZID=Asia/Tokyo:20240102T060000\r\n")
+	assert.Contains(t, ics, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR\r\n")
+	assert.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT"))
+
+	schedules, err := ImportICalendar(&buf, time.Date(2024, 1, 8, 0, 0, 0, 0, tokyo), tokyo)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 2)
+	assert.NoError(t, schedules[0].Err)
+	assert.NoError(t, schedules[1].Err)
+
+	assert.Equal(t, "key1@akerun", schedules[0].UID)
+	assert.Equal(t, "Entrance; Main, 1F", schedules[0].Summary)
+	assert.Equal(t, ScheduleTypeTemporary, schedules[0].Params.ScheduleType)
+	assert.Equal(t, "2024-01-01T09:00:00+09:00", schedules[0].Params.TemporarySchedule.StartDateTime)
+	assert.Equal(t, "2024-01-03T18:00:00+09:00", schedules[0].Params.TemporarySchedule.EndDateTime)
+
+	assert.Equal(t, ScheduleTypeRecurring, schedules[1].Params.ScheduleType)
+	assert.Equal(t, []uint32{1, 3, 5}, schedules[1].Params.RecurringSchedule.DaysOfWeek)
+	assert.Equal(t, "22:00", schedules[1].Params.RecurringSchedule.StartTime)
+	assert.Equal(t, "06:00", schedules[1].Params.RecurringSchedule.EndTime)
+}
+
+func TestExportICalendar_DaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurring := recurringKey("09:00", "18:00", 0)
+	recurring.ID = "key1"
+
+	var buf bytes.Buffer
+	err = ExportICalendar(&buf, []Key{recurring}, time.Date(2024, 3, 1, 0, 0, 0, 0, newYork), newYork)
+	assert.NoError(t, err)
+
+	ics := buf.String()
+	assert.Contains(t, ics, "BEGIN:VTIMEZONE\r\nTZID:America/New_York\r\n")
+	assert.Contains(t, ics, "BEGIN:DAYLIGHT\r\nDTSTART:20230312T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\nEND:DAYLIGHT\r\n")
+	assert.Contains(t, ics, "BEGIN:STANDARD\r\nDTSTART:20231105T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nTZNAME:EST\r\nEND:STANDARD\r\n")
+	assert.Contains(t, ics, "DTSTART;TZID=America/New_York:20240303T090000\r\n")
+}
+
+func TestImportICalendar_UnsupportedRRule(t *testing.T) {
+	event := func(dtstart, rrule, extra string) string {
+		return "BEGIN:VEVENT\r\nUID:event\r\nDTSTART:" + dtstart + "\r\nDTEND:" + dtstart[:9] + "110000\r\nRRULE:" + rrule + "\r\n" + extra + "END:VEVENT\r\n"
+	}
+	now := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		dtstart  string
+		rrule    string
+		extra    string
+		wantDays []uint32
+		wantErr  string
+	}{
+		{"interval 1", "20240110T100000", "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO", "", []uint32{1}, ""},
+		{"wkst", "20240110T100000", "FREQ=WEEKLY;WKST=SU;BYDAY=MO", "", []uint32{1}, ""},
+		{"daily byday", "20240110T100000", "FREQ=DAILY;BYDAY=MO,WE", "", []uint32{1, 3}, ""},
+		{"interval", "20240110T100000", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "", nil, "INTERVAL"},
+		{"until", "20240110T100000", "FREQ=DAILY;UNTIL=20240131T000000Z", "", nil, "UNTIL"},
+		{"count", "20240110T100000", "FREQ=WEEKLY;COUNT=4;BYDAY=MO", "", nil, "COUNT"},
+		{"bymonth", "20240110T100000", "FREQ=WEEKLY;BYDAY=MO;BYMONTH=6", "", nil, "BYMONTH"},
+		{"bysetpos", "20240110T100000", "FREQ=WEEKLY;BYDAY=MO,TU;BYSETPOS=1", "", nil, "BYSETPOS"},
+		{"byhour", "20240110T100000", "FREQ=DAILY;BYHOUR=10,14", "", nil, "BYHOUR"},
+		{"nth byday", "20240110T100000", "FREQ=WEEKLY;BYDAY=1MO", "", nil, "BYDAY"},
+		{"exdate", "20240110T100000", "FREQ=WEEKLY;BYDAY=MO", "EXDATE:20240115T100000\r\n", nil, "EXDATE"},
+		{"rdate", "20240110T100000", "FREQ=WEEKLY;BYDAY=MO", "RDATE:20240116T100000\r\n", nil, "RDATE"},
+		{"exrule", "20240110T100000", "FREQ=WEEKLY;BYDAY=MO", "EXRULE:FREQ=MONTHLY\r\n", nil, "EXRULE"},
+		{"future start", "20240301T100000", "FREQ=WEEKLY;BYDAY=MO", "", nil, "future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedules, err := ImportICalendar(strings.NewReader(event(tt.dtstart, tt.rrule, tt.extra)), now, time.UTC)
+			assert.NoError(t, err)
+			assert.Len(t, schedules, 1)
+			if tt.wantErr == "" {
+				assert.NoError(t, schedules[0].Err)
+				assert.Equal(t, tt.wantDays, schedules[0].Params.RecurringSchedule.DaysOfWeek)
+			} else {
+				assert.ErrorContains(t, schedules[0].Err, tt.wantErr)
+			}
+		})
+	}
+
+	// Dates added to or removed from a single event cannot be kept either
+	schedules, err := ImportICalendar(strings.NewReader("BEGIN:VEVENT\r\nUID:event\r\nDTSTART:20240110T100000\r\nDTEND:20240110T110000\r\nRDATE:20240111T100000\r\nEND:VEVENT\r\n"), now, time.UTC)
+	assert.NoError(t, err)
+	assert.ErrorContains(t, schedules[0].Err, "RDATE")
+}
+
+func TestImportICalendar(t *testing.T) {
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:visit-1\r\n" +
+		"SUMMARY:Visitor from a very long company name that needs to be folded acr\r\n" +
+		" oss lines\r\n" +
+		"DTSTART:20240110T100000\r\n" +
+		"DURATION:PT2H30M\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:cleaning\r\n" +
+		"DTSTART;TZID=UTC:20240110T000000\r\n" +
+		"DTEND;TZID=UTC:20240110T010000\r\n" +
+		"RRULE:FREQ=DAILY\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	schedules, err := ImportICalendar(strings.NewReader(ics), time.Date(2024, 1, 10, 12, 0, 0, 0, tokyo), tokyo)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 2)
+	assert.Equal(t, "Visitor from a very long company name that needs to be folded across lines", schedules[0].Summary)
+	assert.Equal(t, "2024-01-10T10:00:00+09:00", schedules[0].Params.TemporarySchedule.StartDateTime)
+	assert.Equal(t, "2024-01-10T12:30:00+09:00", schedules[0].Params.TemporarySchedule.EndDateTime)
+	assert.Equal(t, "09:00", schedules[1].Params.RecurringSchedule.StartTime)
+	assert.Len(t, schedules[1].Params.RecurringSchedule.DaysOfWeek, 7)
+
+	// An event that cannot be imported does not keep the others from being imported,
+	// and a visit planned after now is imported as usual
+	schedules, err = ImportICalendar(strings.NewReader("BEGIN:VEVENT\r\nDTSTART:20240110T100000\r\nDTEND:20240110T110000\r\nRRULE:FREQ=MONTHLY\r\nEND:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\nDTSTART:20240301T100000\r\nDTEND:20240301T110000\r\nEND:VEVENT\r\n"), time.Date(2024, 2, 1, 0, 0, 0, 0, tokyo), tokyo)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 2)
+	assert.Error(t, schedules[0].Err)
+	assert.NoError(t, schedules[1].Err)
+	assert.Equal(t, "2024-03-01T10:00:00+09:00", schedules[1].Params.TemporarySchedule.StartDateTime)
+
+	_, err = ImportICalendar(strings.NewReader("END:VEVENT\r\n"), time.Date(2024, 2, 1, 0, 0, 0, 0, tokyo), tokyo)
+	assert.Error(t, err)
+}
+
+func TestImportICalendar_ZoneConversion(t *testing.T) {
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	// 20:00 UTC on Monday and Wednesday is 05:00 in Tokyo on Tuesday and Thursday
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:night-shift\r\n" +
+		"DTSTART;TZID=UTC:20240108T200000\r\n" +
+		"DURATION:PT2H\r\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"ACTION:DISPLAY\r\n" +
+		"DESCRIPTION:Reminder\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"DURATION:PT5M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	schedules, err := ImportICalendar(strings.NewReader(ics), time.Date(2024, 2, 1, 0, 0, 0, 0, tokyo), tokyo)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+	assert.NoError(t, schedules[0].Err)
+	assert.Equal(t, "05:00", schedules[0].Params.RecurringSchedule.StartTime)
+	// The DURATION of the alarm does not replace the one of the event
+	assert.Equal(t, "07:00", schedules[0].Params.RecurringSchedule.EndTime)
+	assert.Equal(t, []uint32{2, 4}, schedules[0].Params.RecurringSchedule.DaysOfWeek)
+}
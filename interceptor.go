@@ -0,0 +1,88 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// OperationName represents the name of a client method that calls the Akerun API.
+type OperationName string
+
+// Operation names
+const (
+	OpGetOrganizations      OperationName = "GetOrganizations"
+	OpGetOrganization       OperationName = "GetOrganization"
+	OpGetAkeruns            OperationName = "GetAkeruns"
+	OpGetAkerunGroups       OperationName = "GetAkerunGroups"
+	OpGetAkerunGroup        OperationName = "GetAkerunGroup"
+	OpCreateAkerunGroup     OperationName = "CreateAkerunGroup"
+	OpUpdateAkerunGroup     OperationName = "UpdateAkerunGroup"
+	OpDeleteAkerunGroup     OperationName = "DeleteAkerunGroup"
+	OpAddAkerunToGroup      OperationName = "AddAkerunToGroup"
+	OpRemoveAkerunFromGroup OperationName = "RemoveAkerunFromGroup"
+	OpGetKeys               OperationName = "GetKeys"
+	OpGetKey                OperationName = "GetKey"
+	OpCreateKey             OperationName = "CreateKey"
+	OpUpdateKey             OperationName = "UpdateKey"
+	OpDeleteKey             OperationName = "DeleteKey"
+	OpGetUsers              OperationName = "GetUsers"
+	OpGetUser               OperationName = "GetUser"
+	OpRegisterUser          OperationName = "RegisterUser"
+	OpInviteUser            OperationName = "InviteUser"
+	OpUpdateUser            OperationName = "UpdateUser"
+	OpExitUser              OperationName = "ExitUser"
+	OpGetAccesses           OperationName = "GetAccesses"
+	OpRevoke                OperationName = "Revoke"
+	OpGetTokenInfo          OperationName = "GetTokenInfo"
+	// OpDo is the name of calls made with Client.Do.
+	OpDo OperationName = "Do"
+)
+
+// Operation represents a single call to the Akerun API as seen by interceptors.
+// Interceptors may modify any field before passing the operation on.
+type Operation struct {
+	// Name is the name of the client method.
+	Name OperationName
+	// Method is the HTTP method.
+	Method string
+	// Path is the API path including the version prefix, e.g. "/v3/organizations/O1/users".
+	// OAuth2 endpoints have no version prefix, e.g. "/oauth/revoke".
+	Path string
+	// Query is the query string parameters.
+	Query url.Values
+	// Encoding is how Body is encoded. Operations with EncodingQuery have no body.
+	Encoding Encoding
+	// Body is the request body before encoding. It is url.Values for form bodies and nil if there is no body.
+	Body interface{}
+	// Header is added to the HTTP request.
+	Header http.Header
+	// Token is the OAuth2 token used for the call. It is nil for calls that are not authorized by a token, such as OpRevoke.
+	Token *oauth2.Token
+	// Result is the pointer the response is decoded into, such as *User for OpGetUser or *UsersList for OpGetUsers.
+	// It is nil if the response is discarded.
+	Result interface{}
+
+	// envelope is the key of the object that wraps the result in the response, if any.
+	envelope string
+}
+
+// RoundTrip executes an operation.
+type RoundTrip func(ctx context.Context, op *Operation) error
+
+// Interceptor wraps a RoundTrip to add cross-cutting behavior such as auditing, caching or fault injection.
+// An interceptor may short-circuit the call by returning without calling next,
+// in which case it is responsible for filling the value op.Result points to.
+type Interceptor func(next RoundTrip) RoundTrip
+
+// roundTrip returns the configured interceptors chained around send.
+// The first interceptor is the outermost one.
+func (c *Client) roundTrip() RoundTrip {
+	rt := RoundTrip(c.send)
+	for i := len(c.config.Interceptors) - 1; i >= 0; i-- {
+		rt = c.config.Interceptors[i](rt)
+	}
+	return rt
+}
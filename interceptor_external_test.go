@@ -0,0 +1,67 @@
+package akerun_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestInterceptor_Result(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/organizations/org1/users/user1", r.URL.Path)
+		_, err := w.Write([]byte(`{"user":{"id":"user1","name":"Test User"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	var seen []string
+	// audit reads the decoded result
+	audit := func(next akerun.RoundTrip) akerun.RoundTrip {
+		return func(ctx context.Context, op *akerun.Operation) error {
+			err := next(ctx, op)
+			if user, ok := op.Result.(*akerun.User); ok {
+				seen = append(seen, user.Name)
+			}
+			return err
+		}
+	}
+	// cache answers GetUser for user2 without calling the API
+	cache := func(next akerun.RoundTrip) akerun.RoundTrip {
+		return func(ctx context.Context, op *akerun.Operation) error {
+			user, ok := op.Result.(*akerun.User)
+			if ok && op.Name == akerun.OpGetUser && op.Path == "/v3/organizations/org1/users/user2" {
+				*user = akerun.User{ID: "user2", Name: "Cached User"}
+				return nil
+			}
+			return next(ctx, op)
+		}
+	}
+
+	config := akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Interceptors = []akerun.Interceptor{audit, cache}
+	client := akerun.NewClient(config)
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	user, err := client.GetUser(context.Background(), token, "org1", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test User", user.Name)
+
+	user, err = client.GetUser(context.Background(), token, "org1", "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, "Cached User", user.Name)
+
+	assert.Equal(t, []string{"Test User", "Cached User"}, seen)
+}
@@ -0,0 +1,67 @@
+package akerun
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_Interceptors(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "audit", r.Header.Get("X-Test"))
+		_, err := w.Write([]byte(`{"user":{"id":"user1","name":"Test User"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	var calls []string
+	audit := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, op *Operation) error {
+			op.Header.Set("X-Test", "audit")
+			err := next(ctx, op)
+			calls = append(calls, string(op.Name)+" "+op.Method+" "+op.Path)
+			if user, ok := op.Result.(*User); ok {
+				calls = append(calls, user.Name)
+			}
+			return err
+		}
+	}
+	errInjected := errors.New("injected")
+	faults := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, op *Operation) error {
+			if op.Name == OpExitUser {
+				return errInjected
+			}
+			return next(ctx, op)
+		}
+	}
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Interceptors = []Interceptor{audit, faults}
+	client := NewClient(config)
+
+	token := &oauth2.Token{AccessToken: "test_token"}
+	_, err := client.GetUser(context.Background(), token, "org1", "user1")
+	assert.NoError(t, err)
+	err = client.ExitUser(context.Background(), token, "org1", "user1")
+	assert.ErrorIs(t, err, errInjected)
+
+	assert.Equal(t, []string{
+		"GetUser GET /v3/organizations/org1/users/user1",
+		"Test User",
+		"ExitUser DELETE /v3/organizations/org1/users/user1",
+	}, calls)
+}
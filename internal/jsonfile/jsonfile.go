@@ -0,0 +1,55 @@
+// Package jsonfile reads and atomically replaces JSON files.
+package jsonfile
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Read decodes the JSON file at path into v. It reports false, and leaves v untouched,
+// if the file does not exist.
+func Read(path string, v interface{}) (bool, error) {
+	byt, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(byt, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Write replaces the file at path with v encoded as indented JSON. See WriteFunc.
+func Write(path string, v interface{}) error {
+	return WriteFunc(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
+
+// WriteFunc replaces the file at path with what write writes.
+// The file is written to a temporary file in the same directory first and renamed over path,
+// so readers never see a partial file. A new file is readable by the owner only.
+func WriteFunc(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,33 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	var v map[string]int
+	ok, err := Read(path, &v)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, Write(path, map[string]int{"a": 1}))
+	ok, err = Read(path, &v)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]int{"a": 1}, v)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
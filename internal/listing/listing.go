@@ -0,0 +1,88 @@
+// Package listing fetches every page of the organization resources that the subpackages work on.
+package listing
+
+import (
+	"context"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/Hayao0819/go-akerun/internal/pager"
+	"golang.org/x/oauth2"
+)
+
+// Organizations returns the IDs of the organizations the token can access.
+func Organizations(ctx context.Context, client *akerun.Client, token *oauth2.Token) ([]string, error) {
+	return pager.All(func(idAfter string) ([]string, error) {
+		result, err := client.GetOrganizations(ctx, token, akerun.OrganizationsParameter{Limit: pager.Limit, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		ids := []string{}
+		for _, o := range result.Organizations {
+			ids = append(ids, o.ID)
+		}
+		return ids, nil
+	}, func(id string) string { return id })
+}
+
+// Users returns the users of an organization.
+func Users(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string) ([]akerun.User, error) {
+	return pager.All(func(idAfter string) ([]akerun.User, error) {
+		result, err := client.GetUsers(ctx, token, organizationId, akerun.UsersParameter{Limit: pager.Limit, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		return result.Users, nil
+	}, func(u akerun.User) string { return u.ID })
+}
+
+// Akeruns returns the Akeruns of an organization.
+func Akeruns(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string) ([]akerun.Akerun, error) {
+	return pager.All(func(idAfter string) ([]akerun.Akerun, error) {
+		result, err := client.GetAkeruns(ctx, token, organizationId, akerun.AkerunListParameter{Limit: pager.Limit, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		return result.Akeruns, nil
+	}, func(a akerun.Akerun) string { return a.ID })
+}
+
+// AkerunGroups returns the Akerun groups of an organization with their Akeruns.
+func AkerunGroups(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string) ([]akerun.AkerunGroupDetailed, error) {
+	result, err := client.GetAkerunGroups(ctx, token, organizationId)
+	if err != nil {
+		return nil, err
+	}
+	groups := []akerun.AkerunGroupDetailed{}
+	for _, g := range result.AkerunGroups {
+		group, err := client.GetAkerunGroup(ctx, token, organizationId, g.ID)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *group)
+	}
+	return groups, nil
+}
+
+// Keys returns the keys of an organization.
+func Keys(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string) ([]akerun.Key, error) {
+	return pager.All(func(idAfter string) ([]akerun.Key, error) {
+		result, err := client.GetKeys(ctx, token, organizationId, akerun.KeysParameter{Limit: pager.Limit, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		return result.Keys, nil
+	}, func(k akerun.Key) string { return k.ID })
+}
+
+// Accesses returns the access history of an organization that matches params. Its Limit and IdAfter are ignored.
+func Accesses(ctx context.Context, client *akerun.Client, token *oauth2.Token, organizationId string, params akerun.AccessesParameter) ([]akerun.Access, error) {
+	return pager.All(func(idAfter string) ([]akerun.Access, error) {
+		params.Limit = pager.Limit
+		params.IdAfter = idAfter
+		result, err := client.GetAccesses(ctx, token, organizationId, params)
+		if err != nil {
+			return nil, err
+		}
+		return result.Accesses, nil
+	}, func(a akerun.Access) string { return a.ID })
+}
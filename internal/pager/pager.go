@@ -0,0 +1,23 @@
+// Package pager walks the pages of Akerun API lists, where each page continues after the ID of the last item of the previous one.
+package pager
+
+// Limit is the number of items requested per page.
+const Limit = 100
+
+// All returns the items of every page. fetch returns up to Limit items after the item with the ID idAfter,
+// or the first page if idAfter is empty, and id returns the ID of an item. A page with fewer than Limit items is the last.
+func All[T any](fetch func(idAfter string) ([]T, error), id func(T) string) ([]T, error) {
+	items := []T{}
+	idAfter := ""
+	for {
+		page, err := fetch(idAfter)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if len(page) < Limit {
+			return items, nil
+		}
+		idAfter = id(page[len(page)-1])
+	}
+}
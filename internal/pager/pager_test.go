@@ -0,0 +1,40 @@
+package pager
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	var calls []string
+	// Serve 250 items whose IDs are their positions
+	fetch := func(idAfter string) ([]int, error) {
+		calls = append(calls, idAfter)
+		start := 0
+		if idAfter != "" {
+			n, err := strconv.Atoi(idAfter)
+			if err != nil {
+				return nil, err
+			}
+			start = n + 1
+		}
+		var page []int
+		for i := start; i < 250 && len(page) < Limit; i++ {
+			page = append(page, i)
+		}
+		return page, nil
+	}
+
+	items, err := All(fetch, strconv.Itoa)
+	assert.NoError(t, err)
+	assert.Len(t, items, 250)
+	assert.Equal(t, 249, items[249])
+	assert.Equal(t, []string{"", "99", "199"}, calls)
+
+	errFetch := errors.New("fetch failed")
+	_, err = All(func(string) ([]int, error) { return nil, errFetch }, strconv.Itoa)
+	assert.ErrorIs(t, err, errFetch)
+}
@@ -0,0 +1,27 @@
+// Package wallclock places times of day by what the wall clock reads. On days with a daylight saving
+// transition this differs from adding the time of day to midnight as elapsed time.
+package wallclock
+
+import "time"
+
+// On returns the time on the date of day in loc whose wall clock reads offset from midnight.
+func On(day time.Time, offset time.Duration, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	h, min, sec := offset/time.Hour, offset%time.Hour/time.Minute, offset%time.Minute/time.Second
+	return time.Date(y, m, d, int(h), int(min), int(sec), 0, loc)
+}
+
+// DayOf returns midnight of the day t belongs to in the location of t,
+// given the wall clock time boundary at which days start.
+func DayOf(t time.Time, boundary time.Duration) time.Time {
+	loc := t.Location()
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	for t.Before(On(day, boundary, loc)) {
+		day = day.AddDate(0, 0, -1)
+	}
+	for next := day.AddDate(0, 0, 1); !t.Before(On(next, boundary, loc)); next = day.AddDate(0, 0, 1) {
+		day = next
+	}
+	return day
+}
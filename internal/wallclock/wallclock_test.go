@@ -0,0 +1,66 @@
+package wallclock
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOn(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks go forward on 2024-03-10 and back on 2024-11-03
+	spring := time.Date(2024, 3, 10, 0, 0, 0, 0, newYork)
+	fall := time.Date(2024, 11, 3, 0, 0, 0, 0, newYork)
+
+	tests := []struct {
+		name   string
+		day    time.Time
+		offset time.Duration
+		want   time.Time
+	}{
+		{"spring forward", spring, 9 * time.Hour, time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC)},
+		{"fall back", fall, 9 * time.Hour, time.Date(2024, 11, 3, 14, 0, 0, 0, time.UTC)},
+		{"minutes and seconds", spring, 4*time.Hour + 30*time.Minute + 15*time.Second, time.Date(2024, 3, 10, 8, 30, 15, 0, time.UTC)},
+		{"next day", spring, 25 * time.Hour, time.Date(2024, 3, 11, 5, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := On(tt.day, tt.offset, newYork)
+			assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func TestDayOf(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2024, month, day, hour, min, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"before boundary", at(time.January, 2, 3, 59), at(time.January, 1, 0, 0)},
+		{"at boundary", at(time.January, 2, 4, 0), at(time.January, 2, 0, 0)},
+		{"spring forward before boundary", at(time.March, 10, 3, 59), at(time.March, 9, 0, 0)},
+		{"spring forward at boundary", at(time.March, 10, 4, 0), at(time.March, 10, 0, 0)},
+		{"fall back before boundary", at(time.November, 3, 3, 59), at(time.November, 2, 0, 0)},
+		{"fall back at boundary", at(time.November, 3, 4, 0), at(time.November, 3, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DayOf(tt.t, 4*time.Hour)
+			assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
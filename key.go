@@ -13,6 +13,13 @@ const (
 	apiPathKeys = "keys"
 )
 
+// Key schedule types
+const (
+	ScheduleTypePermanent = "permanent"
+	ScheduleTypeTemporary = "temporary"
+	ScheduleTypeRecurring = "recurring"
+)
+
 type Key struct {
 	ID                string `json:"id"`
 	Role              string `json:"role"`
@@ -38,16 +45,14 @@ type Key struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
 	}
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
 }
 
 type KeysList struct {
 	Keys []Key `json:"keys"`
 }
 
-type keyRow struct {
-	Key Key `json:"key"`
-}
-
 type KeysParameter struct {
 	UserId   string `url:"user_id,omitempty"`
 	AkerunId string `url:"akerun_id,omitempty"`
@@ -67,7 +72,7 @@ func (c *Client) GetKeys(
 	if err != nil {
 		return nil, err
 	}
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathKeys), http.MethodGet, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpGetKeys, path.Join(apiPathOrganizations, organizationId, apiPathKeys), http.MethodGet, oauth2Token, v, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -84,16 +89,16 @@ func (c *Client) GetKey(
 	organizationId string,
 	params KeyParameter) (*Key, error) {
 
-	var result keyRow
+	var result Key
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathKeys), http.MethodGet, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpGetKey, path.Join(apiPathOrganizations, organizationId, apiPathKeys), http.MethodGet, oauth2Token, v, enveloped("key", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.Key, nil
+	return &result, nil
 }
 
 type CreateKeyParameter struct {
@@ -120,18 +125,18 @@ func (c *Client) CreateKey(
 	akerunId string,
 	params CreateKeyParameter,
 ) (*Key, error) {
-	var result keyRow
+	var result Key
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 	v.Add("user_id", userId)
 	v.Add("akerun_id", akerunId)
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathKeys), http.MethodPost, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpCreateKey, path.Join(apiPathOrganizations, organizationId, apiPathKeys), http.MethodPost, oauth2Token, v, enveloped("key", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.Key, nil
+	return &result, nil
 }
 
 type UpdateKeyParameter struct {
@@ -157,17 +162,17 @@ func (c *Client) UpdateKey(
 	schedule_type string,
 	params UpdateKeyParameter,
 ) (*Key, error) {
-	var result keyRow
+	var result Key
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 	v.Add("schedule_type", schedule_type)
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathKeys, keyId), http.MethodPut, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpUpdateKey, path.Join(apiPathOrganizations, organizationId, apiPathKeys, keyId), http.MethodPut, oauth2Token, v, enveloped("key", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.Key, nil
+	return &result, nil
 }
 
 func (c *Client) DeleteKey(
@@ -176,7 +181,7 @@ func (c *Client) DeleteKey(
 	organizationId string,
 	keyId string,
 ) error {
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathKeys, keyId), http.MethodDelete, oauth2Token, nil, nil, nil)
+	err := c.callVersion(ctx, OpDeleteKey, path.Join(apiPathOrganizations, organizationId, apiPathKeys, keyId), http.MethodDelete, oauth2Token, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,161 @@
+package akerun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redacted replaces secret values in logs.
+const redacted = "[REDACTED]"
+
+// sensitiveFields are the query parameters and JSON fields whose values are never logged.
+var sensitiveFields = map[string]bool{
+	"client_secret":    true,
+	"access_token":     true,
+	"refresh_token":    true,
+	"token":            true,
+	"key_url_password": true,
+	// Key URLs open doors for whoever holds them.
+	"key_url": true,
+}
+
+// sensitiveHeaders are the headers whose values are never logged.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// logRequest logs an API request and its response with the configured logger.
+// respBody is the raw response body, or nil if the request failed before a response was received.
+func (c *Client) logRequest(ctx context.Context, req *http.Request, statusCode int, respBody []byte, duration time.Duration, err error) {
+	logger := c.config.Logger
+	if logger == nil {
+		return
+	}
+
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelError
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	reqBody := requestBody(req)
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL)),
+		slog.Any("headers", redactHeader(req.Header)),
+		slog.Int("status", statusCode),
+		slog.Duration("duration", duration),
+		slog.Int("request_body_size", len(reqBody)),
+		slog.Int("response_body_size", len(respBody)),
+	}
+	if c.config.LogBodies {
+		attrs = append(attrs,
+			slog.String("request_body", redactBody(reqBody)),
+			slog.String("response_body", redactBody(respBody)),
+		)
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "akerun api request", attrs...)
+}
+
+// requestBody returns a copy of the request body without consuming it.
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	byt, _ := io.ReadAll(body)
+	return byt
+}
+
+// redactURL returns the URL with the sensitive query parameters redacted.
+func redactURL(u *url.URL) string {
+	r := *u
+	r.RawQuery = redactValues(u.Query()).Encode()
+	return r.String()
+}
+
+// redactValues returns a copy of v with the sensitive values redacted.
+func redactValues(v url.Values) url.Values {
+	r := url.Values{}
+	for key, values := range v {
+		if sensitiveFields[strings.TrimSuffix(key, "[]")] {
+			r[key] = []string{redacted}
+			continue
+		}
+		r[key] = values
+	}
+	return r
+}
+
+// redactHeader returns a copy of h with the sensitive headers redacted.
+func redactHeader(h http.Header) http.Header {
+	r := http.Header{}
+	for key, values := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			r[key] = []string{redacted}
+			continue
+		}
+		r[key] = values
+	}
+	return r
+}
+
+// redactBody returns the body with the sensitive fields redacted.
+// JSON and URL-encoded bodies are supported; other bodies are omitted entirely.
+func redactBody(body []byte) string {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(trimmed, &v); err == nil {
+		byt, err := json.Marshal(redactJSON(v))
+		if err != nil {
+			return redacted
+		}
+		return string(byt)
+	}
+
+	if values, err := url.ParseQuery(string(trimmed)); err == nil {
+		return redactValues(values).Encode()
+	}
+	return redacted
+}
+
+// redactJSON redacts the sensitive fields of a decoded JSON value recursively.
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if sensitiveFields[key] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactJSON(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactJSON(value)
+		}
+	}
+	return v
+}
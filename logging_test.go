@@ -0,0 +1,64 @@
+package akerun
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{``, ``},
+		{`{"client_id":"id","client_secret":"secret","token":"tok"}`, `{"client_id":"id","client_secret":"[REDACTED]","token":"[REDACTED]"}`},
+		{`{"data":[{"access_token":"a","refresh_token":"r"}]}`, `{"data":[{"access_token":"[REDACTED]","refresh_token":"[REDACTED]"}]}`},
+		{`key_url_password=secret&role=user`, `key_url_password=%5BREDACTED%5D&role=user`},
+		{`{"key":{"id":"key1","keys":{"key_url":"https://example.com/k","password_protected":true}}}`, `{"key":{"id":"key1","keys":{"key_url":"[REDACTED]","password_protected":true}}}`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, redactBody([]byte(tt.body)))
+	}
+}
+
+func TestClient_Logger(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"key":{"id":"key1"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	var buf bytes.Buffer
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	config.LogBodies = true
+	client := NewClient(config)
+
+	token := &oauth2.Token{AccessToken: "test_token"}
+	params := CreateKeyParameter{ScheduleType: "permanent", KeyUrlPassword: "p@ssw0rd"}
+	_, err := client.CreateKey(context.Background(), token, "org1", "user1", "akerun1", params)
+	assert.NoError(t, err)
+
+	log := buf.String()
+	assert.Contains(t, log, `"method":"POST"`)
+	assert.Contains(t, log, `"status":200`)
+	assert.Contains(t, log, `"response_body":"{\"key\":{\"id\":\"key1\"}}"`)
+	assert.Contains(t, log, "key_url_password=%5BREDACTED%5D")
+	assert.NotContains(t, log, "p@ssw0rd")
+	assert.NotContains(t, log, "test_token")
+}
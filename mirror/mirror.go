@@ -0,0 +1,292 @@
+// Package mirror keeps a local copy of an Akerun organization and records the changes between syncs.
+package mirror
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+)
+
+// DefaultMaxChanges is the default number of change events kept by a Mirror.
+const DefaultMaxChanges = 10000
+
+// ErrOrganizationMismatch is returned by Open when the store holds the mirror of another organization.
+var ErrOrganizationMismatch = errors.New("mirror: store belongs to another organization")
+
+// ChangeType represents the kind of a change event.
+type ChangeType string
+
+// Change types
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// ResourceType represents the kind of a mirrored resource.
+type ResourceType string
+
+// Resource types
+const (
+	ResourceUser        ResourceType = "user"
+	ResourceAkerun      ResourceType = "akerun"
+	ResourceAkerunGroup ResourceType = "akerun_group"
+	ResourceKey         ResourceType = "key"
+)
+
+// Change represents a change of a resource detected between two syncs.
+type Change struct {
+	Type     ChangeType   `json:"type"`
+	Resource ResourceType `json:"resource"`
+	ID       string       `json:"id"`
+	SyncedAt time.Time    `json:"synced_at"`
+}
+
+// Snapshot represents the state of an organization at a point in time.
+// The key URLs of its keys are left empty.
+type Snapshot struct {
+	OrganizationID string                                `json:"organization_id"`
+	SyncedAt       time.Time                             `json:"synced_at"`
+	Users          map[string]akerun.User                `json:"users"`
+	Akeruns        map[string]akerun.Akerun              `json:"akeruns"`
+	AkerunGroups   map[string]akerun.AkerunGroupDetailed `json:"akerun_groups"`
+	Keys           map[string]akerun.Key                 `json:"keys"`
+}
+
+// newSnapshot creates an empty snapshot.
+func newSnapshot(organizationId string) *Snapshot {
+	return &Snapshot{
+		OrganizationID: organizationId,
+		Users:          map[string]akerun.User{},
+		Akeruns:        map[string]akerun.Akerun{},
+		AkerunGroups:   map[string]akerun.AkerunGroupDetailed{},
+		Keys:           map[string]akerun.Key{},
+	}
+}
+
+// Mirror represents a local copy of an organization.
+// It is safe for concurrent use.
+type Mirror struct {
+	mu         sync.RWMutex
+	store      Store
+	snapshot   *Snapshot
+	changes    []Change
+	maxChanges int
+}
+
+// Open loads the mirror of the organization from store.
+// It returns ErrOrganizationMismatch if the store holds the mirror of another organization.
+func Open(store Store, organizationId string) (*Mirror, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := state.Snapshot
+	if snapshot == nil {
+		snapshot = newSnapshot(organizationId)
+	} else if snapshot.OrganizationID != organizationId {
+		return nil, fmt.Errorf("%w: %s, not %s", ErrOrganizationMismatch, snapshot.OrganizationID, organizationId)
+	}
+
+	return &Mirror{
+		store:      store,
+		snapshot:   snapshot,
+		changes:    state.Changes,
+		maxChanges: DefaultMaxChanges,
+	}, nil
+}
+
+// SetMaxChanges sets the number of change events kept by the mirror.
+func (m *Mirror) SetMaxChanges(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxChanges = n
+}
+
+// apply replaces the snapshot, records the changes and persists the state.
+func (m *Mirror) apply(next *Snapshot) ([]Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var changes []Change
+	changes = append(changes, diff(ResourceUser, m.snapshot.Users, next.Users, next.SyncedAt)...)
+	changes = append(changes, diff(ResourceAkerun, m.snapshot.Akeruns, next.Akeruns, next.SyncedAt)...)
+	changes = append(changes, diff(ResourceAkerunGroup, m.snapshot.AkerunGroups, next.AkerunGroups, next.SyncedAt)...)
+	changes = append(changes, diff(ResourceKey, m.snapshot.Keys, next.Keys, next.SyncedAt)...)
+
+	log := append(m.changes, changes...)
+	if m.maxChanges > 0 && len(log) > m.maxChanges {
+		log = log[len(log)-m.maxChanges:]
+	}
+
+	if err := m.store.Save(&State{Snapshot: next, Changes: log}); err != nil {
+		return nil, err
+	}
+	m.snapshot = next
+	m.changes = log
+	return changes, nil
+}
+
+// diff returns the changes between two sets of resources.
+func diff[T any](resource ResourceType, prev, next map[string]T, syncedAt time.Time) []Change {
+	var changes []Change
+	for _, id := range sortedKeys(next) {
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: ChangeAdded, Resource: resource, ID: id, SyncedAt: syncedAt})
+		case !equal(old, next[id]):
+			changes = append(changes, Change{Type: ChangeModified, Resource: resource, ID: id, SyncedAt: syncedAt})
+		}
+	}
+	for _, id := range sortedKeys(prev) {
+		if _, ok := next[id]; !ok {
+			changes = append(changes, Change{Type: ChangeRemoved, Resource: resource, ID: id, SyncedAt: syncedAt})
+		}
+	}
+	return changes
+}
+
+// equal reports whether two resources have the same JSON encoding.
+// Unknown fields are kept as raw JSON, whose bytes change when the store reformats it, so the values
+// are compared as compact JSON rather than byte by byte.
+func equal[T any](a, b T) bool {
+	aj, aErr := json.Marshal(a)
+	bj, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(aj, bj)
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedValues returns the values of m ordered by key.
+func sortedValues[T any](m map[string]T, filter func(T) bool) []T {
+	var values []T
+	for _, k := range sortedKeys(m) {
+		if filter == nil || filter(m[k]) {
+			values = append(values, m[k])
+		}
+	}
+	return values
+}
+
+// OrganizationID returns the ID of the mirrored organization.
+func (m *Mirror) OrganizationID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshot.OrganizationID
+}
+
+// SyncedAt returns the time of the last successful sync.
+func (m *Mirror) SyncedAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshot.SyncedAt
+}
+
+// Changes returns the change events recorded after since.
+func (m *Mirror) Changes(since time.Time) []Change {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var changes []Change
+	for _, c := range m.changes {
+		if c.SyncedAt.After(since) {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// User returns the user with the specified ID.
+func (m *Mirror) User(id string) (akerun.User, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.snapshot.Users[id]
+	return u, ok
+}
+
+// Users returns the users matching filter. If filter is nil, all users are returned.
+func (m *Mirror) Users(filter func(akerun.User) bool) []akerun.User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedValues(m.snapshot.Users, filter)
+}
+
+// UserByCode returns the user with the specified user code.
+func (m *Mirror) UserByCode(code string) (akerun.User, bool) {
+	users := m.Users(func(u akerun.User) bool { return u.Code == code })
+	if len(users) == 0 {
+		return akerun.User{}, false
+	}
+	return users[0], true
+}
+
+// Akerun returns the Akerun with the specified ID.
+func (m *Mirror) Akerun(id string) (akerun.Akerun, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.snapshot.Akeruns[id]
+	return a, ok
+}
+
+// Akeruns returns the Akeruns matching filter. If filter is nil, all Akeruns are returned.
+func (m *Mirror) Akeruns(filter func(akerun.Akerun) bool) []akerun.Akerun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedValues(m.snapshot.Akeruns, filter)
+}
+
+// AkerunGroups returns all Akerun groups.
+func (m *Mirror) AkerunGroups() []akerun.AkerunGroupDetailed {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedValues(m.snapshot.AkerunGroups, nil)
+}
+
+// AkerunsInGroup returns the Akeruns that belong to the specified Akerun group.
+func (m *Mirror) AkerunsInGroup(akerunGroupId string) []akerun.Akerun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var akeruns []akerun.Akerun
+	for _, a := range m.snapshot.AkerunGroups[akerunGroupId].Akeruns {
+		if found, ok := m.snapshot.Akeruns[a.ID]; ok {
+			akeruns = append(akeruns, found)
+		}
+	}
+	return akeruns
+}
+
+// Keys returns the keys matching filter. If filter is nil, all keys are returned.
+func (m *Mirror) Keys(filter func(akerun.Key) bool) []akerun.Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return sortedValues(m.snapshot.Keys, filter)
+}
+
+// KeysForUser returns the keys issued to the specified user.
+func (m *Mirror) KeysForUser(userId string) []akerun.Key {
+	return m.Keys(func(k akerun.Key) bool { return k.User.ID == userId })
+}
+
+// KeysForAkerun returns the keys issued for the specified Akerun.
+func (m *Mirror) KeysForAkerun(akerunId string) []akerun.Key {
+	return m.Keys(func(k akerun.Key) bool { return k.Akerun.ID == akerunId })
+}
@@ -0,0 +1,97 @@
+package mirror
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestSyncer_Resync(t *testing.T) {
+	userName := "Test User"
+	keys := `{"keys":[{"id":"key1","akerun":{"id":"A1"},"User":{"id":"user1"},"keys":{"key_url":"https://example.com/key1"}}]}`
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/v3/organizations/org1/users":
+			body = `{"users":[{"id":"user1","name":"` + userName + `","code":"E001","profile":{"dept":"x"}}]}`
+		case "/v3/organizations/org1/akeruns":
+			body = `{"akeruns":[{"id":"A1","name":"Door"}]}`
+		case "/v3/organizations/org1/akerun_groups":
+			body = `{"akerun_groups":[{"id":"G1","name":"Group"}]}`
+		case "/v3/organizations/org1/akerun_groups/G1":
+			body = `{"akerun_group":{"id":"G1","name":"Group","akeruns":[{"id":"A1"}]}}`
+		case "/v3/organizations/org1/keys":
+			body = keys
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	store := NewFileStore(path)
+
+	m, err := Open(store, "org1")
+	assert.NoError(t, err)
+
+	// A zero interval is rejected instead of making the ticker panic
+	assert.Error(t, NewSyncer(client, token, m).Run(context.Background(), 0, nil))
+
+	// The first sync adds everything
+	changes, err := NewSyncer(client, token, m).Resync(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, changes, 4)
+	for _, c := range changes {
+		assert.Equal(t, ChangeAdded, c.Type)
+	}
+
+	// Key URLs are not written to the store
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "https://example.com/")
+
+	// The second sync reports only the differences
+	userName = "Renamed User"
+	keys = `{"keys":[]}`
+	changes, err = NewSyncer(client, token, m).Resync(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []ChangeType{ChangeModified, ChangeRemoved}, []ChangeType{changes[0].Type, changes[1].Type})
+	assert.Equal(t, []ResourceType{ResourceUser, ResourceKey}, []ResourceType{changes[0].Resource, changes[1].Resource})
+
+	// The mirror can be reopened from the store and queried
+	m, err = Open(store, "org1")
+	assert.NoError(t, err)
+	user, ok := m.UserByCode("E001")
+	assert.True(t, ok)
+	assert.Equal(t, "Renamed User", user.Name)
+	assert.Len(t, m.AkerunsInGroup("G1"), 1)
+	assert.Empty(t, m.KeysForUser("user1"))
+	assert.Len(t, m.Changes(m.SyncedAt().Add(-1)), 2)
+
+	// Unknown fields read back from the store are not reported as modified
+	changes, err = NewSyncer(client, token, m).Resync(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// A store cannot be reused for another organization
+	_, err = Open(store, "org2")
+	assert.ErrorIs(t, err, ErrOrganizationMismatch)
+}
@@ -0,0 +1,51 @@
+package mirror
+
+import (
+	"sync"
+
+	"github.com/Hayao0819/go-akerun/internal/jsonfile"
+)
+
+// State represents the persisted state of a mirror.
+type State struct {
+	Snapshot *Snapshot `json:"snapshot"`
+	Changes  []Change  `json:"changes"`
+}
+
+// Store represents a persistent storage for the mirror state.
+type Store interface {
+	// Load returns the persisted state. It returns an empty state if nothing has been saved yet.
+	Load() (*State, error)
+	// Save persists the state.
+	Save(state *State) error
+}
+
+// FileStore is a Store that keeps the state in a single JSON file.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a new FileStore that reads and writes the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the state from the file.
+func (s *FileStore) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state State
+	if _, err := jsonfile.Read(s.path, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Save writes the state to the file atomically.
+func (s *FileStore) Save(state *State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return jsonfile.Write(s.path, state)
+}
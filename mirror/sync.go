@@ -0,0 +1,123 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/Hayao0819/go-akerun/internal/listing"
+	"golang.org/x/oauth2"
+)
+
+// Syncer refreshes a Mirror from the Akerun API.
+type Syncer struct {
+	client *akerun.Client
+	token  *oauth2.Token
+	mirror *Mirror
+	now    func() time.Time
+}
+
+// NewSyncer creates a new Syncer that refreshes mirror with client and token.
+func NewSyncer(client *akerun.Client, token *oauth2.Token, mirror *Mirror) *Syncer {
+	return &Syncer{
+		client: client,
+		token:  token,
+		mirror: mirror,
+		now:    time.Now,
+	}
+}
+
+// Resync fetches the full current state of the organization, stores it in the mirror
+// and returns the changes since the previous sync, found by comparing the two states.
+// The Akerun API has no updated-since filters, so every resync lists all users, Akeruns,
+// Akerun groups and keys; pick the interval of Run accordingly.
+// The mirror is left untouched if any request fails.
+func (s *Syncer) Resync(ctx context.Context) ([]Change, error) {
+	organizationId := s.mirror.OrganizationID()
+	next := newSnapshot(organizationId)
+
+	if err := s.fetchUsers(ctx, next); err != nil {
+		return nil, err
+	}
+	if err := s.fetchAkeruns(ctx, next); err != nil {
+		return nil, err
+	}
+	if err := s.fetchAkerunGroups(ctx, next); err != nil {
+		return nil, err
+	}
+	if err := s.fetchKeys(ctx, next); err != nil {
+		return nil, err
+	}
+
+	next.SyncedAt = s.now()
+	return s.mirror.apply(next)
+}
+
+// Run calls Resync every interval until ctx is canceled. It returns an error if interval is not positive.
+// Resync errors are passed to onError if it is not nil, and the next sync is attempted as usual.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("mirror: interval must be positive, got %v", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Resync(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Syncer) fetchUsers(ctx context.Context, snapshot *Snapshot) error {
+	users, err := listing.Users(ctx, s.client, s.token, snapshot.OrganizationID)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		snapshot.Users[u.ID] = u
+	}
+	return nil
+}
+
+func (s *Syncer) fetchAkeruns(ctx context.Context, snapshot *Snapshot) error {
+	akeruns, err := listing.Akeruns(ctx, s.client, s.token, snapshot.OrganizationID)
+	if err != nil {
+		return err
+	}
+	for _, a := range akeruns {
+		snapshot.Akeruns[a.ID] = a
+	}
+	return nil
+}
+
+func (s *Syncer) fetchAkerunGroups(ctx context.Context, snapshot *Snapshot) error {
+	groups, err := listing.AkerunGroups(ctx, s.client, s.token, snapshot.OrganizationID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		snapshot.AkerunGroups[g.ID] = g
+	}
+	return nil
+}
+
+func (s *Syncer) fetchKeys(ctx context.Context, snapshot *Snapshot) error {
+	keys, err := listing.Keys(ctx, s.client, s.token, snapshot.OrganizationID)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		// Key URLs open doors for whoever holds them, so they are not kept in the mirror.
+		k.Keys.KeyUrl = ""
+		snapshot.Keys[k.ID] = k
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ package akerun
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"golang.org/x/oauth2"
 )
@@ -14,6 +18,26 @@ type TokenInfo struct {
 	RefreshToken    string `json:"refresh_token"`
 	CreatedAt       string `json:"created_at"`
 	ExpiresAt       string `json:"expires_at"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
+}
+
+// Created returns the time the token was created.
+func (i *TokenInfo) Created() (time.Time, error) {
+	return parseTokenTime(i.CreatedAt)
+}
+
+// Expires returns the time the access token expires.
+func (i *TokenInfo) Expires() (time.Time, error) {
+	return parseTokenTime(i.ExpiresAt)
+}
+
+// parseTokenTime parses a time of the token information.
+func parseTokenTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("akerun: token information has no time")
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
 // AuthCodeURL returns a URL to OAuth 2.0 provider's consent page that asks for permissions for the required scopes explicitly.
@@ -23,23 +47,40 @@ func (c *Client) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
 
 // Exchange converts an authorization code into a token.
 func (c *Client) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
-	return c.config.Oauth2.Exchange(ctx, code, opts...)
+	return c.config.Oauth2.Exchange(c.httpContext(ctx), code, opts...)
 }
 
 // RefreshToken returns a new token that carries the same authorization as token, but with a renewed access token.
 func (c *Client) RefreshToken(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error) {
-	return c.config.Oauth2.TokenSource(ctx, token).Token()
+	return c.tokenSource(c.httpContext(ctx), token).Token()
 }
 
-// Revoke revokes the specified OAuth2 token.
+// Token type hints for RevokeToken
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// Revoke revokes the access token of the specified OAuth2 token.
 func (c *Client) Revoke(ctx context.Context, token *oauth2.Token) error {
-	postBody := map[string]string{
-		"client_id":     c.config.Oauth2.ClientID,
-		"client_secret": c.config.Oauth2.ClientSecret,
-		"token":         token.AccessToken,
+	return c.RevokeToken(ctx, token.AccessToken, TokenTypeHintAccessToken)
+}
+
+// RevokeToken revokes an access or refresh token. tokenTypeHint is TokenTypeHintAccessToken,
+// TokenTypeHintRefreshToken or empty if the type is unknown.
+// The request is authenticated by the client credentials only, so the token is never refreshed first.
+// Like other mutating requests, it is not sent in dry-run mode.
+func (c *Client) RevokeToken(ctx context.Context, token string, tokenTypeHint string) error {
+	params := url.Values{
+		"client_id":     {c.config.Oauth2.ClientID},
+		"client_secret": {c.config.Oauth2.ClientSecret},
+		"token":         {token},
+	}
+	if tokenTypeHint != "" {
+		params.Set("token_type_hint", tokenTypeHint)
 	}
 
-	err := c.call(ctx, "oauth/revoke", http.MethodPost, token, nil, postBody, nil)
+	err := c.call(ctx, OpRevoke, "/oauth/revoke", http.MethodPost, nil, params, nil)
 	if err != nil {
 		return err
 	}
@@ -47,10 +88,27 @@ func (c *Client) Revoke(ctx context.Context, token *oauth2.Token) error {
 	return nil
 }
 
+// RevokeAll revokes both the refresh token and the access token of the specified OAuth2 token.
+// It tries both even if one fails.
+func (c *Client) RevokeAll(ctx context.Context, token *oauth2.Token) error {
+	var errs []error
+	if token.RefreshToken != "" {
+		if err := c.RevokeToken(ctx, token.RefreshToken, TokenTypeHintRefreshToken); err != nil {
+			errs = append(errs, fmt.Errorf("revoke refresh token: %w", err))
+		}
+	}
+	if token.AccessToken != "" {
+		if err := c.RevokeToken(ctx, token.AccessToken, TokenTypeHintAccessToken); err != nil {
+			errs = append(errs, fmt.Errorf("revoke access token: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // GetTokenInfo retrieves the token information for the given OAuth2 token.
 func (c *Client) GetTokenInfo(ctx context.Context, token *oauth2.Token) (*TokenInfo, error) {
 	var result TokenInfo
-	err := c.call(ctx, "oauth/token/info", http.MethodGet, token, nil, nil, &result)
+	err := c.call(ctx, OpGetTokenInfo, "/oauth/token/info", http.MethodGet, token, nil, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_RevokeAll(t *testing.T) {
+	var revoked []map[string]string
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/oauth/revoke", r.URL.Path)
+		// Revocation must not try to use or refresh the token being revoked
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.NoError(t, r.ParseForm())
+		body := map[string]string{}
+		for key := range r.PostForm {
+			body[key] = r.PostForm.Get(key)
+		}
+		revoked = append(revoked, body)
+		_, err := w.Write([]byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	manager := NewTokenManager(client, NewMemoryTokenStore())
+	// An expired token is revoked as is instead of being refreshed first
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+	assert.NoError(t, manager.SetToken("a", token))
+
+	err := manager.Revoke(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"client_id": "testId", "client_secret": "testPass", "token": "refresh", "token_type_hint": "refresh_token"},
+		{"client_id": "testId", "client_secret": "testPass", "token": "access", "token_type_hint": "access_token"},
+	}, revoked)
+
+	_, err = manager.Token(context.Background(), "a")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestTokenManager_RevokeDryRun(t *testing.T) {
+	var ops []string
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.DryRun = true
+	config.Interceptors = []Interceptor{
+		func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, op *Operation) error {
+				ops = append(ops, op.Method+" "+op.Path)
+				return next(ctx, op)
+			}
+		},
+	}
+	client := NewClient(config)
+	manager := NewTokenManager(client, NewMemoryTokenStore())
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}
+	assert.NoError(t, manager.SetToken("a", token))
+
+	// Nothing is revoked, so the tokens stay usable
+	assert.NoError(t, manager.Revoke(context.Background(), "a"))
+	assert.Equal(t, []string{"POST /oauth/revoke", "POST /oauth/revoke"}, ops)
+	stored, err := manager.Token(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "access", stored.AccessToken)
+}
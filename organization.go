@@ -23,15 +23,12 @@ type OrganizationList struct {
 	Organizations []id `json:"organizations"`
 }
 
-// organizationRow represents a row in the organization table.
-type organizationRow struct {
-	Organization Organization `json:"organization"`
-}
-
 // Organization represents the detailed information of an organization.
 type Organization struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
 }
 
 // OrganizationsParameter represents the parameters for GetOrganizations method.
@@ -50,7 +47,7 @@ func (c *Client) GetOrganizations(
 	if err != nil {
 		return nil, err
 	}
-	err = c.callVersion(ctx, apiPathOrganizations, http.MethodGet, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpGetOrganizations, apiPathOrganizations, http.MethodGet, oauth2Token, v, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -59,10 +56,10 @@ func (c *Client) GetOrganizations(
 
 // GetOrganization retrieves the details of an organization with the specified ID.
 func (c *Client) GetOrganization(ctx context.Context, oauth2Token *oauth2.Token, id string) (*Organization, error) {
-	var result organizationRow
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, id), http.MethodGet, oauth2Token, nil, nil, &result)
+	var result Organization
+	err := c.callVersion(ctx, OpGetOrganization, path.Join(apiPathOrganizations, id), http.MethodGet, oauth2Token, nil, enveloped("organization", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.Organization, nil
+	return &result, nil
 }
@@ -0,0 +1,127 @@
+package akerun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Hayao0819/go-akerun/internal/pager"
+	"golang.org/x/oauth2"
+)
+
+// The Akerun API v3 describes the Akerun Remote of an Akerun only by the ID embedded in the Akerun,
+// and its connection status is not part of that response. The checks below therefore only tell whether
+// an Akerun exists and is paired with an Akerun Remote; a remote operation still fails
+// while the Akerun Remote is offline. Endpoints without a method can be called with Client.Do.
+
+// Reasons why an Akerun is not paired with an Akerun Remote. RemoteStatus.Err wraps one of them.
+var (
+	ErrAkerunNotFound = errors.New("akerun: Akerun not found")
+	ErrNoAkerunRemote = errors.New("akerun: Akerun is not paired with an Akerun Remote")
+)
+
+// Types of the peripherals of an Akerun
+const (
+	PeripheralNFCReaderInside  = "nfc_reader_inside"
+	PeripheralNFCReaderOutside = "nfc_reader_outside"
+	PeripheralDoorSensor       = "door_sensor"
+)
+
+// Peripheral represents a device attached to an Akerun.
+type Peripheral struct {
+	// Type is one of the Peripheral constants.
+	Type              string
+	ID                string
+	BatteryPercentage int
+	// BatteryUnknown reports that the API did not return the battery level, in which case BatteryPercentage is 0.
+	BatteryUnknown bool
+}
+
+// RemoteStatus represents whether an Akerun is paired with an Akerun Remote, and the peripherals attached to it.
+type RemoteStatus struct {
+	AkerunID string
+	// AkerunRemoteID is the ID of the Akerun Remote the Akerun is paired with, or empty if there is none.
+	AkerunRemoteID string
+	// Peripherals are the peripherals attached to the Akerun.
+	Peripherals []Peripheral
+	// Err is the reason the Akerun is not paired with an Akerun Remote, or nil if it is.
+	// It wraps ErrAkerunNotFound or ErrNoAkerunRemote.
+	Err error
+}
+
+// Paired reports whether the Akerun is paired with an Akerun Remote.
+// It does not tell whether the Akerun Remote is online.
+func (s RemoteStatus) Paired() bool {
+	return s.Err == nil
+}
+
+// RemoteStatuses returns, for each Akerun of an organization, whether it is paired with an Akerun Remote,
+// keyed by Akerun ID. If akerunIds is given, only those Akeruns are checked.
+func (c *Client) RemoteStatuses(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	akerunIds ...string,
+) (map[string]RemoteStatus, error) {
+	akeruns, err := c.allAkeruns(ctx, oauth2Token, organizationId, akerunIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]RemoteStatus, len(akeruns))
+	for _, a := range akeruns {
+		result[a.ID] = remoteStatus(a)
+	}
+	for _, id := range akerunIds {
+		if _, ok := result[id]; !ok {
+			result[id] = RemoteStatus{AkerunID: id, Err: fmt.Errorf("%w (Akerun %s)", ErrAkerunNotFound, id)}
+		}
+	}
+	return result, nil
+}
+
+// CheckRemotePaired returns nil if the Akerun is paired with an Akerun Remote, or the reason it is not.
+// A remote operation can still fail while the Akerun Remote is offline.
+func (c *Client) CheckRemotePaired(
+	ctx context.Context,
+	oauth2Token *oauth2.Token,
+	organizationId string,
+	akerunId string,
+) error {
+	statuses, err := c.RemoteStatuses(ctx, oauth2Token, organizationId, akerunId)
+	if err != nil {
+		return err
+	}
+	return statuses[akerunId].Err
+}
+
+// remoteStatus returns the remote status of an Akerun.
+func remoteStatus(a Akerun) RemoteStatus {
+	status := RemoteStatus{AkerunID: a.ID, AkerunRemoteID: a.AkerunRemote.ID}
+	peripherals := []Peripheral{
+		{Type: PeripheralNFCReaderInside, ID: a.NFCReaderInside.ID, BatteryPercentage: a.NFCReaderInside.BatteryPercentage, BatteryUnknown: a.UnknownBatteries.NFCReaderInside},
+		{Type: PeripheralNFCReaderOutside, ID: a.NFCReaderOutside.ID, BatteryPercentage: a.NFCReaderOutside.BatteryPercentage, BatteryUnknown: a.UnknownBatteries.NFCReaderOutside},
+		{Type: PeripheralDoorSensor, ID: a.DoorSensor.ID, BatteryPercentage: a.DoorSensor.BatteryPercentage, BatteryUnknown: a.UnknownBatteries.DoorSensor},
+	}
+	for _, p := range peripherals {
+		// Peripherals that are not attached have no ID.
+		if p.ID != "" {
+			status.Peripherals = append(status.Peripherals, p)
+		}
+	}
+	if a.AkerunRemote.ID == "" {
+		status.Err = fmt.Errorf("%w (Akerun %s)", ErrNoAkerunRemote, a.ID)
+	}
+	return status
+}
+
+// allAkeruns returns the Akeruns of an organization, or only those in ids if it is not empty.
+func (c *Client) allAkeruns(ctx context.Context, oauth2Token *oauth2.Token, organizationId string, ids []string) ([]Akerun, error) {
+	return pager.All(func(idAfter string) ([]Akerun, error) {
+		list, err := c.GetAkeruns(ctx, oauth2Token, organizationId, AkerunListParameter{Limit: pager.Limit, AkerunIds: ids, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		return list.Akeruns, nil
+	}, func(a Akerun) string { return a.ID })
+}
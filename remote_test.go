@@ -0,0 +1,58 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_RemoteStatuses(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/organizations/org1/akeruns", r.URL.Path)
+		// The first Akerun is the one of the GetAkeruns test, the others differ in their Akerun Remote
+		_, err := w.Write([]byte(`{"akeruns":[
+			{"id":"A1030000","name":"オーナー0の部屋1","image_url":null,"autolock":false,"open_door_alert":false,"open_door_alert_second":null,"push_button":false,"normal_sound_volume":null,"alert_sound_volume":null,"battery_percentage":null,"seconds_till_autolock":null,"lock_type":null,"autolock_off_schedule":null,"akerun_remote":{"id":"TG11160000"},"nfc_reader_inside":{"id":"N1030000","battery_percentage":null},"nfc_reader_outside":{"id":"N0030000","battery_percentage":null},"door_sensor":{"id":"W1030000","battery_percentage":null}},
+			{"id":"A2","akerun_remote":null},
+			{"id":"A3"}
+		]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	statuses, err := client.RemoteStatuses(context.Background(), token, "org1", "A1030000", "A2", "A3", "A4")
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 4)
+
+	assert.True(t, statuses["A1030000"].Paired())
+	assert.Equal(t, "TG11160000", statuses["A1030000"].AkerunRemoteID)
+	assert.Equal(t, []Peripheral{
+		{Type: PeripheralNFCReaderInside, ID: "N1030000", BatteryUnknown: true},
+		{Type: PeripheralNFCReaderOutside, ID: "N0030000", BatteryUnknown: true},
+		{Type: PeripheralDoorSensor, ID: "W1030000", BatteryUnknown: true},
+	}, statuses["A1030000"].Peripherals)
+	assert.False(t, statuses["A2"].Paired())
+	assert.Empty(t, statuses["A2"].Peripherals)
+	assert.ErrorIs(t, statuses["A2"].Err, ErrNoAkerunRemote)
+	assert.EqualError(t, statuses["A2"].Err, "akerun: Akerun is not paired with an Akerun Remote (Akerun A2)")
+	assert.ErrorIs(t, statuses["A3"].Err, ErrNoAkerunRemote)
+	assert.Empty(t, statuses["A3"].AkerunRemoteID)
+	assert.ErrorIs(t, statuses["A4"].Err, ErrAkerunNotFound)
+
+	assert.NoError(t, client.CheckRemotePaired(context.Background(), token, "org1", "A1030000"))
+	assert.ErrorIs(t, client.CheckRemotePaired(context.Background(), token, "org1", "A2"), ErrNoAkerunRemote)
+}
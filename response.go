@@ -0,0 +1,128 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limit and request ID headers. The first header present in a response is used.
+var (
+	rateLimitLimitHeaders     = []string{"X-RateLimit-Limit", "X-Rate-Limit-Limit", "RateLimit-Limit"}
+	rateLimitRemainingHeaders = []string{"X-RateLimit-Remaining", "X-Rate-Limit-Remaining", "RateLimit-Remaining"}
+	rateLimitResetHeaders     = []string{"X-RateLimit-Reset", "X-Rate-Limit-Reset", "RateLimit-Reset"}
+	requestIDHeaders          = []string{"X-Request-Id", "X-Amzn-Trace-Id"}
+)
+
+// RateLimit represents the rate limit state reported by the Akerun API.
+type RateLimit struct {
+	// Limit is the number of requests allowed in the current window.
+	Limit int
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+	// Reset is when the current window ends. It is zero if the API did not report it.
+	Reset time.Time
+}
+
+// Response represents the HTTP response of an API call.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the raw response body.
+	Body []byte
+	// RequestID is the ID the API assigned to the request, if any.
+	RequestID string
+	// RateLimit is the rate limit state after the request. It is nil if the response had no rate limit headers.
+	RateLimit *RateLimit
+}
+
+// responseKey is the context key of the responseSink to fill.
+type responseKey struct{}
+
+// responseSink is the Response to fill, guarded against calls that share the context concurrently.
+type responseSink struct {
+	mu   sync.Mutex
+	resp *Response
+}
+
+// WithResponse returns a context that makes API calls fill resp with their HTTP response,
+// including when the API returns an error. If several calls share the context, resp holds the last response.
+// Calls that share the context may run concurrently, but resp must only be read after they have returned.
+// resp is left untouched by calls that send no request, such as mutations in dry-run mode.
+func WithResponse(ctx context.Context, resp *Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, &responseSink{resp: resp})
+}
+
+// newResponse creates a Response from an HTTP response and its body.
+func newResponse(res *http.Response, body []byte, now time.Time) *Response {
+	return &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+		RequestID:  firstHeader(res.Header, requestIDHeaders),
+		RateLimit:  parseRateLimit(res.Header, now),
+	}
+}
+
+// parseRateLimit parses the rate limit headers. It returns nil if there are none.
+func parseRateLimit(header http.Header, now time.Time) *RateLimit {
+	remaining, err := strconv.Atoi(firstHeader(header, rateLimitRemainingHeaders))
+	if err != nil {
+		return nil
+	}
+	r := &RateLimit{Remaining: remaining}
+	r.Limit, _ = strconv.Atoi(firstHeader(header, rateLimitLimitHeaders))
+
+	if reset, err := strconv.ParseInt(firstHeader(header, rateLimitResetHeaders), 10, 64); err == nil {
+		// The reset is either a Unix time or a number of seconds from now.
+		if reset > 1e9 {
+			r.Reset = time.Unix(reset, 0)
+		} else {
+			r.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+	return r
+}
+
+// firstHeader returns the value of the first of the headers present.
+func firstHeader(header http.Header, names []string) string {
+	for _, name := range names {
+		if v := header.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// rateLimitState holds the last rate limit state seen by a client.
+type rateLimitState struct {
+	mu        sync.Mutex
+	rateLimit *RateLimit
+}
+
+// recordResponse keeps the rate limit state of resp and passes resp to the caller if requested.
+func (c *Client) recordResponse(ctx context.Context, resp *Response) {
+	if resp.RateLimit != nil {
+		c.rateLimit.mu.Lock()
+		c.rateLimit.rateLimit = resp.RateLimit
+		c.rateLimit.mu.Unlock()
+	}
+	if sink, ok := ctx.Value(responseKey{}).(*responseSink); ok && sink.resp != nil {
+		sink.mu.Lock()
+		*sink.resp = *resp
+		sink.mu.Unlock()
+	}
+}
+
+// RateLimit returns the rate limit state reported by the last response that had one.
+// It returns false if no response has reported it yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	if c.rateLimit.rateLimit == nil {
+		return RateLimit{}, false
+	}
+	return *c.rateLimit.rateLimit, true
+}
@@ -0,0 +1,108 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_WithResponse(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-"+r.URL.Path[len(r.URL.Path)-1:])
+		if r.URL.Path == "/v3/organizations/org2" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "300")
+		w.Header().Set("X-RateLimit-Remaining", "299")
+		w.Header().Set("X-RateLimit-Reset", "1704067200")
+		_, err := w.Write([]byte(`{"organization":{"id":"org1","name":"Test Org"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	_, ok := client.RateLimit()
+	assert.False(t, ok)
+
+	var resp Response
+	org, err := client.GetOrganization(WithResponse(context.Background(), &resp), token, "org1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Org", org.Name)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "req-1", resp.RequestID)
+	assert.Equal(t, `{"organization":{"id":"org1","name":"Test Org"}}`, string(resp.Body))
+	assert.Equal(t, &RateLimit{Limit: 300, Remaining: 299, Reset: time.Unix(1704067200, 0)}, resp.RateLimit)
+
+	// Error responses are captured too, and the last rate limit state is kept
+	_, err = client.GetOrganization(WithResponse(context.Background(), &resp), token, "org2")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "req-2", resp.RequestID)
+	assert.Nil(t, resp.RateLimit)
+
+	rateLimit, ok := client.RateLimit()
+	assert.True(t, ok)
+	assert.Equal(t, 299, rateLimit.Remaining)
+}
+
+func TestClient_WithResponse_Concurrent(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"organization":{"id":"org1","name":"Test Org"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	// Calls sharing the context fill resp without racing each other
+	var resp Response
+	ctx := WithResponse(context.Background(), &resp)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetOrganization(ctx, token, "org1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	header := http.Header{}
+	header.Set("RateLimit-Remaining", "5")
+	header.Set("RateLimit-Reset", "30")
+	assert.Equal(t, &RateLimit{Remaining: 5, Reset: now.Add(30 * time.Second)}, parseRateLimit(header, now))
+
+	assert.Nil(t, parseRateLimit(http.Header{}, now))
+}
@@ -0,0 +1,223 @@
+package akerun
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Hayao0819/go-akerun/internal/wallclock"
+)
+
+// Schedule represents the parsed schedule of a key.
+type Schedule struct {
+	Type string
+
+	// Start and End bound a temporary schedule.
+	Start time.Time
+	End   time.Time
+
+	// Days, StartTime and EndTime describe a recurring schedule.
+	// StartTime and EndTime are offsets from midnight in the location the schedule is evaluated in.
+	// If EndTime is not after StartTime, the window ends on the following day.
+	Days      [7]bool
+	StartTime time.Duration
+	EndTime   time.Duration
+}
+
+// ParseSchedule parses the schedule of a key.
+func ParseSchedule(k Key) (*Schedule, error) {
+	s := &Schedule{Type: k.ScheduleType}
+	switch k.ScheduleType {
+	case ScheduleTypePermanent:
+		return s, nil
+	case ScheduleTypeTemporary:
+		var err error
+		if s.Start, err = time.Parse(time.RFC3339, k.TemporarySchedule.StartDateTime); err != nil {
+			return nil, fmt.Errorf("akerun: invalid start_datetime of key %s: %w", k.ID, err)
+		}
+		if s.End, err = time.Parse(time.RFC3339, k.TemporarySchedule.EndDateTime); err != nil {
+			return nil, fmt.Errorf("akerun: invalid end_datetime of key %s: %w", k.ID, err)
+		}
+		return s, nil
+	case ScheduleTypeRecurring:
+		for _, d := range k.RecurringSchedule.DaysOfWeek {
+			if d > uint32(time.Saturday) {
+				return nil, fmt.Errorf("akerun: invalid day of week %d of key %s", d, k.ID)
+			}
+			s.Days[d] = true
+		}
+		var err error
+		if s.StartTime, err = parseClock(k.RecurringSchedule.StartTime); err != nil {
+			return nil, fmt.Errorf("akerun: invalid start_time of key %s: %w", k.ID, err)
+		}
+		if s.EndTime, err = parseClock(k.RecurringSchedule.EndTime); err != nil {
+			return nil, fmt.Errorf("akerun: invalid end_time of key %s: %w", k.ID, err)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("akerun: unknown schedule type %q of key %s", k.ScheduleType, k.ID)
+	}
+}
+
+// parseClock parses a time of day such as "09:00" or "09:00:00" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day %q", s)
+}
+
+// Allowed reports whether the schedule allows access at t.
+// Recurring schedules are evaluated in loc, or in time.Local if loc is nil.
+func (s *Schedule) Allowed(t time.Time, loc *time.Location) bool {
+	loc = scheduleLocation(loc)
+	switch s.Type {
+	case ScheduleTypePermanent:
+		return true
+	case ScheduleTypeTemporary:
+		return !t.Before(s.Start) && t.Before(s.End)
+	case ScheduleTypeRecurring:
+		// A window that started on the previous day may still be open.
+		for _, w := range s.windows(t.In(loc).AddDate(0, 0, -1), 2, loc) {
+			if !t.Before(w[0]) && t.Before(w[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// windows returns the recurring windows starting on the days days from the date of from.
+func (s *Schedule) windows(from time.Time, days int, loc *time.Location) [][2]time.Time {
+	var windows [][2]time.Time
+	y, m, d := from.In(loc).Date()
+	for i := 0; i < days; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, loc)
+		if !s.Days[day.Weekday()] {
+			continue
+		}
+		start := wallclock.On(day, s.StartTime, loc)
+		end := wallclock.On(day, s.EndTime, loc)
+		if !end.After(start) {
+			end = wallclock.On(day.AddDate(0, 0, 1), s.EndTime, loc)
+		}
+		windows = append(windows, [2]time.Time{start, end})
+	}
+	return windows
+}
+
+// scheduleLocation returns the location recurring schedules are evaluated in, defaulting to time.Local.
+func scheduleLocation(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.Local
+	}
+	return loc
+}
+
+// boundaries returns the instants after t where the schedule may change, in ascending order.
+func (s *Schedule) boundaries(t time.Time, loc *time.Location) []time.Time {
+	loc = scheduleLocation(loc)
+	var candidates []time.Time
+	switch s.Type {
+	case ScheduleTypeTemporary:
+		candidates = []time.Time{s.Start, s.End}
+	case ScheduleTypeRecurring:
+		// Every weekday pattern repeats within a week, so eight days ahead are enough.
+		for _, w := range s.windows(t.In(loc).AddDate(0, 0, -1), 9, loc) {
+			candidates = append(candidates, w[0], w[1])
+		}
+	}
+
+	var after []time.Time
+	for _, c := range candidates {
+		if c.After(t) {
+			after = append(after, c)
+		}
+	}
+	return after
+}
+
+// AllowedAt reports whether the key allows access at t.
+// Recurring schedules are evaluated in loc, or in time.Local if loc is nil.
+func (k Key) AllowedAt(t time.Time, loc *time.Location) (bool, error) {
+	return AccessAllowed([]Key{k}, t, loc)
+}
+
+// NextTransition returns the first instant after t at which the key starts or stops allowing access,
+// and whether access is allowed from then on. ok is false if access never changes.
+func (k Key) NextTransition(t time.Time, loc *time.Location) (next time.Time, allowed bool, ok bool, err error) {
+	return NextAccessTransition([]Key{k}, t, loc)
+}
+
+// AccessAllowed reports whether any of keys allows access at t.
+// Recurring schedules are evaluated in loc, or in time.Local if loc is nil.
+func AccessAllowed(keys []Key, t time.Time, loc *time.Location) (bool, error) {
+	schedules, err := parseSchedules(keys)
+	if err != nil {
+		return false, err
+	}
+	return anyAllowed(schedules, t, loc), nil
+}
+
+// NextAccessTransition returns the first instant after t at which the access granted by keys changes,
+// and whether access is allowed from then on. ok is false if access never changes.
+func NextAccessTransition(keys []Key, t time.Time, loc *time.Location) (next time.Time, allowed bool, ok bool, err error) {
+	schedules, err := parseSchedules(keys)
+	if err != nil {
+		return time.Time{}, false, false, err
+	}
+
+	// Recurring boundaries are only listed for about a week after an instant, so they are also listed after
+	// every temporary boundary: a recurring key can only change the access a week or more after the last
+	// boundary if it never changes it on its own.
+	anchors := []time.Time{t}
+	for _, s := range schedules {
+		if s.Type == ScheduleTypeTemporary {
+			anchors = append(anchors, s.boundaries(t, loc)...)
+		}
+	}
+	var candidates []time.Time
+	for _, s := range schedules {
+		if s.Type != ScheduleTypeRecurring {
+			candidates = append(candidates, s.boundaries(t, loc)...)
+			continue
+		}
+		for _, anchor := range anchors {
+			candidates = append(candidates, s.boundaries(anchor, loc)...)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	current := anyAllowed(schedules, t, loc)
+	for _, c := range candidates {
+		if state := anyAllowed(schedules, c, loc); state != current {
+			return c, state, true, nil
+		}
+	}
+	return time.Time{}, current, false, nil
+}
+
+// parseSchedules parses the schedules of keys.
+func parseSchedules(keys []Key) ([]*Schedule, error) {
+	schedules := make([]*Schedule, 0, len(keys))
+	for _, k := range keys {
+		s, err := ParseSchedule(k)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// anyAllowed reports whether any of schedules allows access at t.
+func anyAllowed(schedules []*Schedule, t time.Time, loc *time.Location) bool {
+	for _, s := range schedules {
+		if s.Allowed(t, loc) {
+			return true
+		}
+	}
+	return false
+}
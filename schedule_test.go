@@ -0,0 +1,161 @@
+package akerun
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recurringKey(start, end string, days ...uint32) Key {
+	k := Key{ID: "key", ScheduleType: ScheduleTypeRecurring}
+	k.RecurringSchedule.StartTime = start
+	k.RecurringSchedule.EndTime = end
+	k.RecurringSchedule.DaysOfWeek = days
+	return k
+}
+
+func temporaryKey(start, end string) Key {
+	k := Key{ID: "key", ScheduleType: ScheduleTypeTemporary}
+	k.TemporarySchedule.StartDateTime = start
+	k.TemporarySchedule.EndDateTime = end
+	return k
+}
+
+func TestKey_AllowedAt(t *testing.T) {
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	// 2024-01-01 is a Monday
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 1, day, hour, min, 0, 0, tokyo) }
+
+	tests := []struct {
+		name string
+		key  Key
+		t    time.Time
+		want bool
+	}{
+		{"permanent", Key{ScheduleType: ScheduleTypePermanent}, at(1, 3, 0), true},
+		{"temporary before", temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-01T18:00:00+09:00"), at(1, 8, 59), false},
+		{"temporary start", temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-01T18:00:00+09:00"), at(1, 9, 0), true},
+		{"temporary end", temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-01T18:00:00+09:00"), at(1, 18, 0), false},
+		{"temporary other zone", temporaryKey("2024-01-01T00:00:00Z", "2024-01-01T01:00:00Z"), at(1, 9, 30), true},
+		{"recurring weekday", recurringKey("09:00", "18:00", 1, 2, 3, 4, 5), at(1, 12, 0), true},
+		{"recurring weekend", recurringKey("09:00", "18:00", 1, 2, 3, 4, 5), at(6, 12, 0), false},
+		{"recurring after hours", recurringKey("09:00", "18:00", 1, 2, 3, 4, 5), at(1, 18, 0), false},
+		{"overnight evening", recurringKey("22:00", "06:00", 1), at(1, 23, 0), true},
+		{"overnight next morning", recurringKey("22:00", "06:00", 1), at(2, 5, 59), true},
+		{"overnight not started on next day", recurringKey("22:00", "06:00", 1), at(2, 23, 0), false},
+		{"overnight previous morning", recurringKey("22:00", "06:00", 1), at(1, 5, 0), false},
+		{"whole day", recurringKey("00:00", "00:00", 0), at(7, 23, 59), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.key.AllowedAt(tt.t, tokyo)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNextAccessTransition(t *testing.T) {
+	tokyo := time.FixedZone("Asia/Tokyo", 9*60*60)
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 1, day, hour, min, 0, 0, tokyo) }
+
+	tests := []struct {
+		name        string
+		keys        []Key
+		t           time.Time
+		wantNext    time.Time
+		wantAllowed bool
+		wantOK      bool
+	}{
+		{"permanent never changes", []Key{{ScheduleType: ScheduleTypePermanent}}, at(1, 0, 0), time.Time{}, true, false},
+		{"temporary start", []Key{temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-01T18:00:00+09:00")}, at(1, 0, 0), at(1, 9, 0), true, true},
+		{"temporary end", []Key{temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-01T18:00:00+09:00")}, at(1, 10, 0), at(1, 18, 0), false, true},
+		{"temporary expired", []Key{temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-01T18:00:00+09:00")}, at(2, 0, 0), time.Time{}, false, false},
+		{"recurring friday to monday", []Key{recurringKey("09:00", "18:00", 1, 2, 3, 4, 5)}, at(5, 19, 0), at(8, 9, 0), true, true},
+		{"overnight end", []Key{recurringKey("22:00", "06:00", 1)}, at(1, 23, 0), at(2, 6, 0), false, true},
+		{
+			"adjacent keys are merged",
+			[]Key{recurringKey("09:00", "12:00", 1), recurringKey("12:00", "18:00", 1)},
+			at(1, 10, 0), at(1, 18, 0), false, true,
+		},
+		{
+			"recurring key outlasts a temporary key weeks ahead",
+			[]Key{temporaryKey("2024-01-01T09:00:00+09:00", "2024-01-29T10:00:00+09:00"), recurringKey("09:00", "18:00", 1)},
+			at(1, 12, 0), at(29, 18, 0), false, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, allowed, ok, err := NextAccessTransition(tt.keys, tt.t, tokyo)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantAllowed, allowed)
+			assert.True(t, tt.wantNext.Equal(next), "next = %v, want %v", next, tt.wantNext)
+		})
+	}
+}
+
+func TestKey_AllowedAt_DaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks go forward on 2024-03-10 and back on 2024-11-03, both Sundays
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2024, month, day, hour, min, 0, 0, newYork)
+	}
+	sunday := recurringKey("09:00", "18:00", 0)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"spring forward start", at(time.March, 10, 9, 0), true},
+		{"spring forward before start", at(time.March, 10, 8, 59), false},
+		{"spring forward end", at(time.March, 10, 18, 0), false},
+		{"fall back start", at(time.November, 3, 9, 0), true},
+		{"fall back before start", at(time.November, 3, 8, 59), false},
+		{"fall back end", at(time.November, 3, 18, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sunday.AllowedAt(tt.t, newYork)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	next, allowed, ok, err := sunday.NextTransition(at(time.March, 10, 0, 0), newYork)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, allowed)
+	assert.True(t, at(time.March, 10, 9, 0).Equal(next), "next = %v", next)
+}
+
+func TestKey_AllowedAt_NilLocation(t *testing.T) {
+	now := time.Now()
+	got, err := recurringKey("00:00", "00:00", uint32(now.In(time.Local).Weekday())).AllowedAt(now, nil)
+	assert.NoError(t, err)
+	assert.True(t, got)
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{"unknown type", Key{ScheduleType: "sometimes"}},
+		{"invalid datetime", temporaryKey("tomorrow", "2024-01-01T18:00:00+09:00")},
+		{"invalid time", recurringKey("9am", "18:00", 1)},
+		{"invalid day", recurringKey("09:00", "18:00", 7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchedule(tt.key)
+			assert.Error(t, err)
+		})
+	}
+}
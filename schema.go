@@ -0,0 +1,112 @@
+package akerun
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaError reports the differences between a JSON payload and the model it was decoded into.
+type SchemaError struct {
+	// Unknown is the paths of the fields the model has no field for, e.g. "users[0].nickname".
+	Unknown []string
+	// Missing is the paths of the model fields without omitempty that the payload lacks.
+	Missing []string
+}
+
+// Error returns the error message.
+func (e *SchemaError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown fields "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing fields "+strings.Join(e.Missing, ", "))
+	}
+	return "akerun: response does not match the model: " + strings.Join(parts, "; ")
+}
+
+// CheckSchema compares the JSON payload data with the model v, a pointer to or value of the type it is decoded into.
+// It returns a *SchemaError listing unknown and missing fields, or nil if they match.
+// It is useful to test recorded payloads against the models in CI.
+func CheckSchema(data []byte, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+	e := &SchemaError{}
+	checkSchema("", data, t, e)
+	if len(e.Unknown) == 0 && len(e.Missing) == 0 {
+		return nil
+	}
+	sort.Strings(e.Unknown)
+	sort.Strings(e.Missing)
+	return e
+}
+
+// checkSchema compares raw with the type t and records the differences in e.
+func checkSchema(path string, raw json.RawMessage, t reflect.Type, e *SchemaError) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface {
+		if t.Kind() == reflect.Interface {
+			return
+		}
+		t = t.Elem()
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return
+		}
+		known := jsonFields(t)
+		seen := map[string]bool{}
+		for name, value := range fields {
+			f, ok := known.lookup(name)
+			if !ok {
+				e.Unknown = append(e.Unknown, joinPath(path, name))
+				continue
+			}
+			seen[f.name] = true
+			checkSchema(joinPath(path, f.name), value, f.typ, e)
+		}
+		for _, f := range known {
+			if !seen[f.name] && !f.omitempty {
+				e.Missing = append(e.Missing, joinPath(path, f.name))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		// Byte slices such as json.RawMessage hold arbitrary JSON.
+		if t.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return
+		}
+		for i, item := range items {
+			checkSchema(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), e)
+		}
+	case reflect.Map:
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return
+		}
+		for key, item := range items {
+			checkSchema(joinPath(path, key), item, t.Elem(), e)
+		}
+	}
+}
+
+// joinPath appends a field name to a path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,67 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestCheckSchema(t *testing.T) {
+	data := []byte(`{"users":[{"id":"user1","name":"Test User","mail":"","image_url":"","authority":"","code":"","nfcs":[{"id":"nfc1","name":"Card","kind":"felica"}],"nickname":"tester"}]}`)
+	err := CheckSchema(data, &UsersList{})
+	var schemaErr *SchemaError
+	assert.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, []string{"users[0].nfcs[0].kind", "users[0].nickname"}, schemaErr.Unknown)
+	assert.Empty(t, schemaErr.Missing)
+
+	err = CheckSchema([]byte(`{"id":"org1"}`), Organization{})
+	assert.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, []string{"name"}, schemaErr.Missing)
+
+	assert.NoError(t, CheckSchema([]byte(`{"id":"org1","name":"Test Org"}`), &Organization{}))
+}
+
+func TestClient_StrictDecoding(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"organization":{"id":"org1","name":"Test Org","plan":"enterprise"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	token := &oauth2.Token{AccessToken: "test_token"}
+
+	// By default unknown fields are kept
+	org, err := NewClient(config).GetOrganization(context.Background(), token, "org1")
+	assert.NoError(t, err)
+	assert.Equal(t, Extra{"plan": []byte(`"enterprise"`)}, org.Extra)
+
+	// The method returns no result, but interceptors see the decoded response
+	var seen *Organization
+	config.StrictDecoding = true
+	config.Interceptors = []Interceptor{func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, op *Operation) error {
+			err := next(ctx, op)
+			seen, _ = op.Result.(*Organization)
+			return err
+		}
+	}}
+	org, err = NewClient(config).GetOrganization(context.Background(), token, "org1")
+	var schemaErr *SchemaError
+	assert.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, []string{"organization.plan"}, schemaErr.Unknown)
+	assert.Nil(t, org)
+	assert.Equal(t, "Test Org", seen.Name)
+}
@@ -0,0 +1,156 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/oauth2"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer and meter.
+const instrumentationName = "github.com/Hayao0819/go-akerun"
+
+// Attribute keys recorded on spans and metrics.
+const (
+	attrHTTPMethod     = attribute.Key("http.request.method")
+	attrHTTPStatusCode = attribute.Key("http.response.status_code")
+	attrEndpoint       = attribute.Key("akerun.endpoint")
+	attrErrorType      = attribute.Key("error.type")
+	attrResendCount    = attribute.Key("http.request.resend_count")
+)
+
+// telemetry holds the OpenTelemetry instruments of a client.
+type telemetry struct {
+	tracer        trace.Tracer
+	duration      metric.Float64Histogram
+	errors        metric.Int64Counter
+	refreshes     metric.Int64Counter
+	refreshErrors metric.Int64Counter
+}
+
+// telemetryHolder lazily creates the telemetry of a client.
+type telemetryHolder struct {
+	once sync.Once
+	t    *telemetry
+}
+
+// telemetry returns the OpenTelemetry instruments configured for the client.
+// If no providers are configured, no-op instruments are returned.
+func (c *Client) telemetry() *telemetry {
+	c.tel.once.Do(func() {
+		tp := c.config.TracerProvider
+		if tp == nil {
+			tp = tracenoop.NewTracerProvider()
+		}
+		mp := c.config.MeterProvider
+		if mp == nil {
+			mp = metricnoop.NewMeterProvider()
+		}
+
+		meter := mp.Meter(instrumentationName)
+		t := &telemetry{tracer: tp.Tracer(instrumentationName)}
+		t.duration, _ = meter.Float64Histogram("akerun.client.request.duration",
+			metric.WithUnit("s"), metric.WithDescription("Duration of Akerun API requests."))
+		t.errors, _ = meter.Int64Counter("akerun.client.request.errors",
+			metric.WithDescription("Number of failed Akerun API requests."))
+		t.refreshes, _ = meter.Int64Counter("akerun.client.token.refreshes",
+			metric.WithDescription("Number of OAuth2 token refreshes."))
+		t.refreshErrors, _ = meter.Int64Counter("akerun.client.token.refresh_errors",
+			metric.WithDescription("Number of failed OAuth2 token refreshes."))
+		c.tel.t = t
+	})
+	return c.tel.t
+}
+
+// startRequestSpan starts a span for an API request and injects the trace context into its headers.
+// The client sends every request once, so the span always records a resend count of 0.
+// The returned function ends the span and records the metrics.
+func (c *Client) startRequestSpan(ctx context.Context, req *http.Request) (context.Context, func(statusCode int, err error)) {
+	t := c.telemetry()
+	endpoint := endpointTemplate(req.URL.Path)
+	attrs := []attribute.KeyValue{
+		attrHTTPMethod.String(req.Method),
+		attrEndpoint.String(endpoint),
+	}
+
+	ctx, span := t.tracer.Start(ctx, req.Method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(attrResendCount.Int(0)),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	start := time.Now()
+
+	return ctx, func(statusCode int, err error) {
+		if statusCode != 0 {
+			attrs = append(attrs, attrHTTPStatusCode.Int(statusCode))
+			span.SetAttributes(attrHTTPStatusCode.Int(statusCode))
+		}
+		if err != nil {
+			attrs = append(attrs, attrErrorType.String(errorType(statusCode)))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			t.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+		}
+		t.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		span.End()
+	}
+}
+
+// errorType returns the value of the error.type attribute for a failed request.
+func errorType(statusCode int) string {
+	if statusCode == 0 {
+		return "transport"
+	}
+	return http.StatusText(statusCode)
+}
+
+// endpointTemplate replaces the IDs in an API path with placeholders,
+// e.g. "/v3/organizations/O1/users/U1" becomes "v3/organizations/{id}/users/{id}".
+func endpointTemplate(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, s := range segments {
+		if s != apiPathOrganizations {
+			continue
+		}
+		for j := i + 1; j < len(segments); j += 2 {
+			segments[j] = "{id}"
+		}
+		break
+	}
+	return strings.Join(segments, "/")
+}
+
+// tracingTokenSource is an oauth2.TokenSource that records a span for every token refresh.
+type tracingTokenSource struct {
+	ctx context.Context
+	src oauth2.TokenSource
+	t   *telemetry
+}
+
+// Token refreshes the token.
+func (s *tracingTokenSource) Token() (*oauth2.Token, error) {
+	ctx, span := s.t.tracer.Start(s.ctx, "akerun.token.refresh", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	token, err := s.src.Token()
+	s.t.refreshes.Add(ctx, 1)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.t.refreshErrors.Add(ctx, 1)
+		return nil, err
+	}
+	return token, nil
+}
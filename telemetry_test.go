@@ -0,0 +1,91 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/oauth2"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v3/organizations", "v3/organizations"},
+		{"/v3/organizations/org1", "v3/organizations/{id}"},
+		{"/v3/organizations/org1/users/user1", "v3/organizations/{id}/users/{id}"},
+		{"/v3/organizations/org1/akerun_groups/g1/akeruns", "v3/organizations/{id}/akerun_groups/{id}/akeruns"},
+		{"/oauth/token/info", "oauth/token/info"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, endpointTemplate(tt.path))
+	}
+}
+
+func TestClient_Telemetry(t *testing.T) {
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"access_token":"new_token","refresh_token":"new_refresh","expires_in":3600}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+		case "/v3/organizations/org1/users/user1":
+			assert.Equal(t, "Bearer new_token", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	recorder := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	config := NewConfig("testId", "testPass", "http://localhost:8080/callback")
+	config.Oauth2.Endpoint.TokenURL = ts.URL + "/oauth/token"
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	config.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	client := NewClient(config)
+
+	// An expired token is refreshed before the request is sent
+	token := &oauth2.Token{AccessToken: "old_token", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+	_, err := client.GetUser(context.Background(), token, "org1", "user1")
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "akerun.token.refresh", spans[0].Name())
+	assert.Equal(t, "GET v3/organizations/{id}/users/{id}", spans[1].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("http.request.resend_count", 0))
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	names := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names[m.Name] = true
+		}
+	}
+	assert.True(t, names["akerun.client.request.duration"])
+	assert.True(t, names["akerun.client.request.errors"])
+	assert.True(t, names["akerun.client.token.refreshes"])
+}
@@ -0,0 +1,102 @@
+package akerun
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// maxCachedTokens bounds the number of refreshed tokens a client keeps.
+const maxCachedTokens = 1024
+
+// tokenCache holds the tokens a client has refreshed, keyed by the token they were refreshed from.
+// Requests made with an expired token share its refreshed token instead of refreshing it again.
+type tokenCache struct {
+	mu      sync.Mutex
+	entries map[string]*tokenEntry
+}
+
+// tokenEntry holds the current token for a cache key. Its mutex ensures one refresh at a time.
+type tokenEntry struct {
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// tokenCacheKey returns the cache key of a token. Tokens are identified by their refresh token,
+// which stays the same while the access token is refreshed.
+func tokenCacheKey(token *oauth2.Token) string {
+	if token.RefreshToken != "" {
+		return "refresh:" + token.RefreshToken
+	}
+	return "access:" + token.AccessToken
+}
+
+// entry returns the cache entry for the token, creating it if needed.
+func (c *tokenCache) entry(token *oauth2.Token) *tokenEntry {
+	key := tokenCacheKey(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		return e
+	}
+	if c.entries == nil {
+		c.entries = map[string]*tokenEntry{}
+	}
+	if len(c.entries) >= maxCachedTokens {
+		c.evict()
+	}
+	e := &tokenEntry{}
+	c.entries[key] = e
+	return e
+}
+
+// evict removes the entries without a valid token, or an arbitrary entry if all are valid.
+// The caller must hold c.mu.
+func (c *tokenCache) evict() {
+	for key, e := range c.entries {
+		if e.mu.TryLock() {
+			valid := e.token.Valid()
+			e.mu.Unlock()
+			if !valid {
+				delete(c.entries, key)
+			}
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < maxCachedTokens {
+			return
+		}
+		delete(c.entries, key)
+	}
+}
+
+// authToken returns the token to authorize a request made with oauth2Token.
+// A valid oauth2Token is used as is. An expired one is refreshed once and the result is
+// shared by all requests made with it until that expires too, when it is refreshed in turn.
+func (c *Client) authToken(ctx context.Context, oauth2Token *oauth2.Token) (*oauth2.Token, error) {
+	if oauth2Token.Valid() {
+		return oauth2Token, nil
+	}
+
+	e := c.tokens.entry(oauth2Token)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token.Valid() {
+		return e.token, nil
+	}
+
+	// The server may rotate refresh tokens, so refresh from the latest one once there is one.
+	refreshToken := oauth2Token.RefreshToken
+	if e.token != nil && e.token.RefreshToken != "" {
+		refreshToken = e.token.RefreshToken
+	}
+	refresher := c.config.Oauth2.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken})
+	token, err := (&tracingTokenSource{ctx: ctx, src: refresher, t: c.telemetry()}).Token()
+	if err != nil {
+		return nil, err
+	}
+	e.token = token
+	return token, nil
+}
@@ -0,0 +1,128 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_SharedRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	// Create a test server to mock the API and token responses
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			refreshes.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"refreshed","refresh_token":"refresh2","token_type":"Bearer","expires_in":7200}`))
+			return
+		}
+		assert.Equal(t, "Bearer refreshed", r.Header.Get("Authorization"))
+		_, err := w.Write([]byte(`{"organization":{"id":"org1","name":"Test Org"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "expired", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetOrganization(context.Background(), token, "org1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Later calls with the same expired token reuse the refreshed one
+	_, err := client.GetOrganization(context.Background(), token, "org1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), refreshes.Load())
+}
+
+func TestClient_RefreshRotatedToken(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		refreshTokens []string
+		authorization []string
+	)
+	// Create a test server that rotates the refresh token on every refresh
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/oauth/token" {
+			assert.NoError(t, r.ParseForm())
+			refreshToken := r.PostForm.Get("refresh_token")
+			refreshTokens = append(refreshTokens, refreshToken)
+			w.Header().Set("Content-Type", "application/json")
+			var body string
+			switch refreshToken {
+			case "refresh1":
+				// Tokens that expire within a few seconds are treated as expired already
+				body = `{"access_token":"access2","refresh_token":"refresh2","token_type":"Bearer","expires_in":1}`
+			case "refresh2":
+				body = `{"access_token":"access3","refresh_token":"refresh3","token_type":"Bearer","expires_in":1}`
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				body = `{"error":"invalid_grant"}`
+			}
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		_, err := w.Write([]byte(`{"organization":{"id":"org1","name":"Test Org"}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "access1", RefreshToken: "refresh1", Expiry: time.Now().Add(-time.Hour)}
+
+	// The refreshed token expires too, and is refreshed with the refresh token it came with
+	for i := 0; i < 2; i++ {
+		_, err := client.GetOrganization(context.Background(), token, "org1")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"refresh1", "refresh2"}, refreshTokens)
+	assert.Equal(t, []string{"Bearer access2", "Bearer access3"}, authorization)
+}
+
+func TestTokenCache_Evict(t *testing.T) {
+	var c tokenCache
+	for i := 0; i < maxCachedTokens+10; i++ {
+		e := c.entry(&oauth2.Token{AccessToken: string(rune('a' + i%26)), RefreshToken: time.Duration(i).String()})
+		if i%2 == 0 {
+			e.token = &oauth2.Token{AccessToken: "valid"}
+		}
+	}
+	assert.LessOrEqual(t, len(c.entries), maxCachedTokens)
+}
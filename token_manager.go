@@ -0,0 +1,362 @@
+package akerun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Hayao0819/go-akerun/internal/pager"
+	"golang.org/x/oauth2"
+)
+
+// ErrTokenNotFound is returned when no token is stored for an account.
+var ErrTokenNotFound = errors.New("akerun: token not found")
+
+// TokenStore stores OAuth2 tokens by account name.
+// Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Load returns the token of the account, or ErrTokenNotFound.
+	Load(account string) (*oauth2.Token, error)
+	// Save stores the token of the account.
+	Save(account string, token *oauth2.Token) error
+	// Delete removes the token of the account.
+	Delete(account string) error
+	// Accounts returns the names of the accounts with a stored token.
+	Accounts() ([]string, error)
+}
+
+// MemoryTokenStore is a TokenStore that keeps tokens in memory.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]oauth2.Token
+}
+
+// NewMemoryTokenStore creates a new empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]oauth2.Token{}}
+}
+
+// Load returns the token of the account.
+func (s *MemoryTokenStore) Load(account string) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[account]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+// Save stores the token of the account.
+func (s *MemoryTokenStore) Save(account string, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[account] = *token
+	return nil
+}
+
+// Delete removes the token of the account.
+func (s *MemoryTokenStore) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, account)
+	return nil
+}
+
+// Accounts returns the names of the accounts with a stored token in sorted order.
+func (s *MemoryTokenStore) Accounts() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts := make([]string, 0, len(s.tokens))
+	for account := range s.tokens {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	return accounts, nil
+}
+
+// tokenCall represents an in-flight token refresh.
+type tokenCall struct {
+	done  chan struct{}
+	token *oauth2.Token
+	err   error
+}
+
+// TokenManager manages the tokens of several Akerun accounts.
+// Expired tokens are refreshed on demand, with at most one refresh in flight per account.
+// It is safe for concurrent use.
+type TokenManager struct {
+	client *Client
+	store  TokenStore
+
+	mu      sync.Mutex
+	calls   map[string]*tokenCall
+	clients map[string]*AccountClient
+}
+
+// NewTokenManager creates a new TokenManager that refreshes tokens with client and keeps them in store.
+func NewTokenManager(client *Client, store TokenStore) *TokenManager {
+	return &TokenManager{
+		client:  client,
+		store:   store,
+		calls:   map[string]*tokenCall{},
+		clients: map[string]*AccountClient{},
+	}
+}
+
+// SetToken stores the token of the account, adding the account if it is new.
+func (m *TokenManager) SetToken(account string, token *oauth2.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.Save(account, token)
+}
+
+// RemoveAccount removes the token of the account.
+func (m *TokenManager) RemoveAccount(account string) error {
+	m.mu.Lock()
+	delete(m.clients, account)
+	m.mu.Unlock()
+	return m.store.Delete(account)
+}
+
+// Revoke revokes the refresh and access tokens of the account and removes them from the store.
+// The tokens are removed even if revocation fails, since they must not be used any more.
+// In dry-run mode nothing is revoked, so the tokens are kept.
+func (m *TokenManager) Revoke(ctx context.Context, account string) error {
+	token, err := m.store.Load(account)
+	if err != nil {
+		return fmt.Errorf("account %s: %w", account, err)
+	}
+	if m.client.config.DryRun {
+		return m.client.RevokeAll(ctx, token)
+	}
+	return errors.Join(m.client.RevokeAll(ctx, token), m.RemoveAccount(account))
+}
+
+// Accounts returns the names of the managed accounts.
+func (m *TokenManager) Accounts() ([]string, error) {
+	return m.store.Accounts()
+}
+
+// storedToken returns the stored token of the account as it is, without refreshing it.
+func (m *TokenManager) storedToken(account string) (*oauth2.Token, error) {
+	return m.store.Load(account)
+}
+
+// setExpiry records the expiry of the token of the account, learned after token was read.
+// Nothing is changed if the stored token already has an expiry, or has been replaced
+// or is being refreshed in the meantime.
+func (m *TokenManager) setExpiry(account string, token *oauth2.Token, expiry time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.calls[account]; ok {
+		return nil
+	}
+	current, err := m.store.Load(account)
+	if err != nil {
+		return err
+	}
+	if current.AccessToken != token.AccessToken || !current.Expiry.IsZero() {
+		return nil
+	}
+	updated := *current
+	updated.Expiry = expiry
+	return m.store.Save(account, &updated)
+}
+
+// Token returns a valid token of the account, refreshing and storing it if it has expired.
+// Concurrent calls for the same account share a single refresh.
+func (m *TokenManager) Token(ctx context.Context, account string) (*oauth2.Token, error) {
+	return m.token(ctx, account, false)
+}
+
+// Refresh refreshes the token of the account even if it has not expired yet and returns the new token.
+func (m *TokenManager) Refresh(ctx context.Context, account string) (*oauth2.Token, error) {
+	return m.token(ctx, account, true)
+}
+
+// token returns the token of the account, refreshing it if it has expired or force is set.
+func (m *TokenManager) token(ctx context.Context, account string, force bool) (*oauth2.Token, error) {
+	m.mu.Lock()
+	call, ok := m.calls[account]
+	if !ok {
+		token, err := m.store.Load(account)
+		if err != nil {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("account %s: %w", account, err)
+		}
+		if !force && token.Valid() {
+			m.mu.Unlock()
+			return token, nil
+		}
+
+		call = &tokenCall{done: make(chan struct{})}
+		m.calls[account] = call
+		// The refresh is shared, so it must not be canceled by the caller that started it.
+		go m.refresh(context.WithoutCancel(ctx), account, token, call)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh refreshes the token of the account and completes call.
+func (m *TokenManager) refresh(ctx context.Context, account string, token *oauth2.Token, call *tokenCall) {
+	defer close(call.done)
+
+	// Drop the access token so that it is refreshed even if it has not expired yet.
+	newToken, err := m.client.RefreshToken(ctx, &oauth2.Token{RefreshToken: token.RefreshToken})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.calls, account)
+
+	if err == nil {
+		err = m.saveRefreshed(account, token, newToken)
+	}
+	if err != nil {
+		call.err = fmt.Errorf("account %s: refresh token: %w", account, err)
+	} else {
+		call.token = newToken
+	}
+}
+
+// saveRefreshed stores newToken, refreshed from token, as the token of the account.
+// Nothing is stored if the stored token has been replaced or removed during the refresh,
+// so that a token from a new authorization is not overwritten by one from the old grant.
+// The caller must hold m.mu.
+func (m *TokenManager) saveRefreshed(account string, token, newToken *oauth2.Token) error {
+	current, err := m.store.Load(account)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.RefreshToken != token.RefreshToken {
+		return nil
+	}
+	return m.store.Save(account, newToken)
+}
+
+// AccountClient is a Client that authenticates as a managed account.
+// Methods called with a nil token use the account's token, refreshing it when needed.
+type AccountClient struct {
+	*Client
+	manager *TokenManager
+	account string
+}
+
+// ClientFor returns a client for the account.
+func (m *TokenManager) ClientFor(account string) *AccountClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.clients[account]; ok {
+		return c
+	}
+
+	config := *m.client.config
+	config.Interceptors = append([]Interceptor{m.tokenInterceptor(account)}, m.client.config.Interceptors...)
+	c := &AccountClient{
+		Client:  NewClient(&config),
+		manager: m,
+		account: account,
+	}
+	m.clients[account] = c
+	return c
+}
+
+// Account returns the name of the account.
+func (c *AccountClient) Account() string {
+	return c.account
+}
+
+// Token returns a valid token of the account.
+func (c *AccountClient) Token(ctx context.Context) (*oauth2.Token, error) {
+	return c.manager.Token(ctx, c.account)
+}
+
+// TokenSource returns an oauth2.TokenSource that returns the tokens of the account.
+// Refreshed tokens are written back to the manager's store.
+func (m *TokenManager) TokenSource(ctx context.Context, account string) oauth2.TokenSource {
+	return &accountTokenSource{ctx: ctx, manager: m, account: account}
+}
+
+// accountTokenSource is an oauth2.TokenSource backed by a TokenManager.
+type accountTokenSource struct {
+	ctx     context.Context
+	manager *TokenManager
+	account string
+}
+
+// Token returns a valid token of the account.
+func (s *accountTokenSource) Token() (*oauth2.Token, error) {
+	return s.manager.Token(s.ctx, s.account)
+}
+
+// tokenInterceptor fills in the token of the account for operations called without one.
+// Revocation is authorized by the client credentials, so it is left alone.
+func (m *TokenManager) tokenInterceptor(account string) Interceptor {
+	return func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, op *Operation) error {
+			if op.Token == nil && op.Name != OpRevoke {
+				token, err := m.Token(ctx, account)
+				if err != nil {
+					return err
+				}
+				op.Token = token
+			}
+			return next(ctx, op)
+		}
+	}
+}
+
+// Organizations returns the IDs of the organizations each account can access, keyed by account name.
+// Accounts that fail are left out and their errors are joined into the returned error.
+func (m *TokenManager) Organizations(ctx context.Context) (map[string][]string, error) {
+	accounts, err := m.store.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string][]string{}
+	var errs []error
+	for _, account := range accounts {
+		ids, err := m.ClientFor(account).organizationIDs(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", account, err))
+			continue
+		}
+		result[account] = ids
+	}
+	return result, errors.Join(errs...)
+}
+
+// organizationIDs returns the IDs of all organizations the account can access.
+func (c *AccountClient) organizationIDs(ctx context.Context) ([]string, error) {
+	orgs, err := pager.All(func(idAfter string) ([]id, error) {
+		list, err := c.GetOrganizations(ctx, nil, OrganizationsParameter{Limit: pager.Limit, IdAfter: idAfter})
+		if err != nil {
+			return nil, err
+		}
+		return list.Organizations, nil
+	}, func(org id) string { return org.ID })
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, org := range orgs {
+		ids = append(ids, org.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,167 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenManager(t *testing.T) {
+	var refreshes atomic.Int32
+	// Create a test server to mock the API and token responses
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/oauth/token":
+			refreshes.Add(1)
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "refresh_a", r.PostForm.Get("refresh_token"))
+			// Give the other callers time to pile up behind the refresh
+			time.Sleep(50 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/json")
+			body = `{"access_token":"access_a2","refresh_token":"refresh_a2","token_type":"Bearer","expires_in":7200}`
+		case "/v3/organizations":
+			switch r.Header.Get("Authorization") {
+			case "Bearer access_a2":
+				body = `{"organizations":[{"id":"org1"},{"id":"org2"}]}`
+			case "Bearer access_b":
+				body = `{"organizations":[{"id":"org3"}]}`
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				body = `{"message":"unauthorized"}`
+			}
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	manager := NewTokenManager(client, NewMemoryTokenStore())
+	assert.NoError(t, manager.SetToken("a", &oauth2.Token{AccessToken: "access_a", RefreshToken: "refresh_a", Expiry: time.Now().Add(-time.Hour)}))
+	assert.NoError(t, manager.SetToken("b", &oauth2.Token{AccessToken: "access_b", RefreshToken: "refresh_b", Expiry: time.Now().Add(time.Hour)}))
+
+	// Concurrent callers share a single refresh
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := manager.Token(context.Background(), "a")
+			assert.NoError(t, err)
+			assert.Equal(t, "access_a2", token.AccessToken)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), refreshes.Load())
+
+	// The refreshed token is stored
+	token, err := manager.Token(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh_a2", token.RefreshToken)
+	assert.Equal(t, int32(1), refreshes.Load())
+
+	token, err = manager.TokenSource(context.Background(), "b").Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "access_b", token.AccessToken)
+
+	// Clients use the account's token when called without one
+	assert.Same(t, manager.ClientFor("b"), manager.ClientFor("b"))
+	orgs, err := manager.ClientFor("b").GetOrganizations(context.Background(), nil, OrganizationsParameter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "org3", orgs.Organizations[0].ID)
+
+	all, err := manager.Organizations(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"a": {"org1", "org2"}, "b": {"org3"}}, all)
+
+	_, err = manager.Token(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestTokenManager_SetExpiry(t *testing.T) {
+	m := NewTokenManager(NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback")), NewMemoryTokenStore())
+	expiry := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	read := &oauth2.Token{AccessToken: "access_a", RefreshToken: "refresh_a"}
+
+	// A token replaced after it was read keeps the new value
+	assert.NoError(t, m.SetToken("a", &oauth2.Token{AccessToken: "access_a2", RefreshToken: "refresh_a2"}))
+	assert.NoError(t, m.setExpiry("a", read, expiry))
+	token, err := m.storedToken("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "access_a2", token.AccessToken)
+	assert.True(t, token.Expiry.IsZero())
+
+	// The token that was read gets the expiry
+	assert.NoError(t, m.SetToken("a", read))
+	assert.NoError(t, m.setExpiry("a", read, expiry))
+	token, err = m.storedToken("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh_a", token.RefreshToken)
+	assert.True(t, expiry.Equal(token.Expiry))
+
+	// An expiry that is already known is kept
+	assert.NoError(t, m.setExpiry("a", read, expiry.Add(time.Hour)))
+	token, err = m.storedToken("a")
+	assert.NoError(t, err)
+	assert.True(t, expiry.Equal(token.Expiry))
+}
+
+func TestTokenManager_RefreshReplaced(t *testing.T) {
+	refreshing := make(chan struct{})
+	replaced := make(chan struct{})
+	// Create a test server to mock the token response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(refreshing)
+		<-replaced
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"access_token":"access_a2","refresh_token":"refresh_a2","token_type":"Bearer","expires_in":7200}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	m := NewTokenManager(NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback")), NewMemoryTokenStore())
+	assert.NoError(t, m.SetToken("a", &oauth2.Token{AccessToken: "access_a", RefreshToken: "refresh_a", Expiry: time.Now().Add(-time.Hour)}))
+
+	refreshed := make(chan error)
+	go func() {
+		_, err := m.Refresh(context.Background(), "a")
+		refreshed <- err
+	}()
+
+	// The account is authorized again while the old token is being refreshed
+	<-refreshing
+	assert.NoError(t, m.SetToken("a", &oauth2.Token{AccessToken: "access_new", RefreshToken: "refresh_new", Expiry: time.Now().Add(time.Hour)}))
+	close(replaced)
+	assert.NoError(t, <-refreshed)
+
+	// The token of the new authorization is kept
+	token, err := m.storedToken("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh_new", token.RefreshToken)
+}
@@ -0,0 +1,383 @@
+package akerun
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Hayao0819/go-akerun/internal/jsonfile"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+)
+
+// Key derivation functions of encrypted token files
+const (
+	kdfScrypt = "scrypt"
+	kdfNone   = "none"
+)
+
+// Parameters of the encrypted token file format
+const (
+	encryptedTokenFileVersion = 1
+	encryptionKeySize         = 32
+	saltSize                  = 16
+)
+
+// ErrDecrypt is returned when a stored token cannot be decrypted, usually because the key is wrong.
+var ErrDecrypt = errors.New("akerun: failed to decrypt token")
+
+// EncryptionKey represents the key used to encrypt stored tokens.
+// It is either a passphrase, from which the key is derived with scrypt, or a raw 256-bit key.
+type EncryptionKey struct {
+	passphrase []byte
+	raw        []byte
+}
+
+// PassphraseKey returns an EncryptionKey derived from the passphrase.
+func PassphraseKey(passphrase string) EncryptionKey {
+	return EncryptionKey{passphrase: []byte(passphrase)}
+}
+
+// RawKey returns an EncryptionKey that uses the 32-byte key as is.
+func RawKey(key []byte) (EncryptionKey, error) {
+	if len(key) != encryptionKeySize {
+		return EncryptionKey{}, fmt.Errorf("akerun: key must be %d bytes, got %d", encryptionKeySize, len(key))
+	}
+	return EncryptionKey{raw: append([]byte{}, key...)}, nil
+}
+
+// KeyFromFile reads a base64-encoded 32-byte key from the file at path.
+func KeyFromFile(path string) (EncryptionKey, error) {
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return EncryptionKey{}, err
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(byt)))
+	if err != nil {
+		return EncryptionKey{}, fmt.Errorf("akerun: invalid key file %s: %w", path, err)
+	}
+	return RawKey(key)
+}
+
+// GenerateKeyFile writes a new random key readable by KeyFromFile to the file at path.
+// It fails if the file already exists.
+func GenerateKeyFile(path string) error {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// kdf returns the name of the key derivation function of the key.
+func (k EncryptionKey) kdf() string {
+	if k.raw != nil {
+		return kdfNone
+	}
+	return kdfScrypt
+}
+
+// cipher returns the AEAD for the key and salt.
+func (k EncryptionKey) cipher(params *scryptParams, salt []byte) (cipher.AEAD, error) {
+	key := k.raw
+	if key == nil {
+		var err error
+		key, err = scrypt.Key(k.passphrase, salt, params.N, params.R, params.P, encryptionKeySize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// scryptParams represents the cost parameters of scrypt.
+type scryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+// defaultScryptParams are the scrypt parameters recommended for interactive logins.
+var defaultScryptParams = scryptParams{N: 1 << 15, R: 8, P: 1}
+
+// encryptedTokenFile represents the content of an encrypted token file.
+// Each token is sealed separately with its account name as additional data.
+type encryptedTokenFile struct {
+	Version int               `json:"version"`
+	KDF     string            `json:"kdf"`
+	Scrypt  *scryptParams     `json:"scrypt,omitempty"`
+	Salt    []byte            `json:"salt,omitempty"`
+	Tokens  map[string][]byte `json:"tokens"`
+}
+
+// newEncryptedTokenFile returns an empty token file for the key.
+// A passphrase key uses salt, or a new random salt if salt is nil.
+func newEncryptedTokenFile(key EncryptionKey, salt []byte) (*encryptedTokenFile, error) {
+	file := &encryptedTokenFile{Version: encryptedTokenFileVersion, KDF: key.kdf(), Tokens: map[string][]byte{}}
+	if file.KDF != kdfScrypt {
+		return file, nil
+	}
+	params := defaultScryptParams
+	file.Scrypt = &params
+	file.Salt = salt
+	if file.Salt == nil {
+		file.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(file.Salt); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// EncryptedFileTokenStore is a TokenStore that keeps tokens in a file, encrypted with AES-GCM.
+// It is safe for concurrent use.
+type EncryptedFileTokenStore struct {
+	path string
+	key  EncryptionKey
+
+	mu sync.Mutex
+	// aead is derived from key for the salt of the file it was last used with.
+	aead cipher.AEAD
+	salt []byte
+}
+
+// NewEncryptedFileTokenStore creates a new EncryptedFileTokenStore that reads and writes the file at path.
+// If the file exists, the key is checked against it.
+func NewEncryptedFileTokenStore(path string, key EncryptionKey) (*EncryptedFileTokenStore, error) {
+	s := &EncryptedFileTokenStore{path: path, key: key}
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := s.cipher(file)
+	if err != nil {
+		return nil, err
+	}
+	for account, sealed := range file.Tokens {
+		if _, err := open(aead, account, sealed); err != nil {
+			return nil, err
+		}
+		break
+	}
+	return s, nil
+}
+
+// Load returns the token of the account.
+func (s *EncryptedFileTokenStore) Load(account string) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	sealed, ok := file.Tokens[account]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	aead, err := s.cipher(file)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, account, sealed)
+}
+
+// Save encrypts and stores the token of the account.
+func (s *EncryptedFileTokenStore) Save(account string, token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	aead, err := s.cipher(file)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(aead, account, token)
+	if err != nil {
+		return err
+	}
+	file.Tokens[account] = sealed
+	return s.write(file)
+}
+
+// Delete removes the token of the account.
+func (s *EncryptedFileTokenStore) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Tokens[account]; !ok {
+		return nil
+	}
+	delete(file.Tokens, account)
+	return s.write(file)
+}
+
+// Accounts returns the names of the accounts with a stored token in sorted order.
+func (s *EncryptedFileTokenStore) Accounts() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]string, 0, len(file.Tokens))
+	for account := range file.Tokens {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	return accounts, nil
+}
+
+// Rotate re-encrypts every stored token with newKey and uses it from then on.
+// Nothing is changed if any token cannot be decrypted with the current key.
+func (s *EncryptedFileTokenStore) Rotate(newKey EncryptionKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	aead, err := s.cipher(file)
+	if err != nil {
+		return err
+	}
+	tokens := map[string]*oauth2.Token{}
+	for account, sealed := range file.Tokens {
+		token, err := open(aead, account, sealed)
+		if err != nil {
+			return fmt.Errorf("account %s: %w", account, err)
+		}
+		tokens[account] = token
+	}
+
+	rotated, err := newEncryptedTokenFile(newKey, nil)
+	if err != nil {
+		return err
+	}
+	newAEAD, err := newKey.cipher(rotated.Scrypt, rotated.Salt)
+	if err != nil {
+		return err
+	}
+	for account, token := range tokens {
+		if rotated.Tokens[account], err = seal(newAEAD, account, token); err != nil {
+			return err
+		}
+	}
+	if err := s.write(rotated); err != nil {
+		return err
+	}
+
+	s.key = newKey
+	s.aead = newAEAD
+	s.salt = rotated.Salt
+	return nil
+}
+
+// read reads the token file. A missing file is read as an empty file for the current key.
+// The caller must hold s.mu, except during construction.
+func (s *EncryptedFileTokenStore) read() (*encryptedTokenFile, error) {
+	byt, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Reuse the salt of the cached key so that it is not derived again until the file is written.
+		return newEncryptedTokenFile(s.key, s.salt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedTokenFile
+	if err := json.Unmarshal(byt, &file); err != nil {
+		return nil, fmt.Errorf("akerun: invalid token file %s: %w", s.path, err)
+	}
+	if file.Version != encryptedTokenFileVersion {
+		return nil, fmt.Errorf("akerun: unsupported token file version %d", file.Version)
+	}
+	if file.KDF != s.key.kdf() {
+		return nil, fmt.Errorf("akerun: token file is encrypted with a %s key, got a %s key", file.KDF, s.key.kdf())
+	}
+	if file.KDF == kdfScrypt && file.Scrypt == nil {
+		return nil, fmt.Errorf("akerun: token file %s has no scrypt parameters", s.path)
+	}
+	if file.Tokens == nil {
+		file.Tokens = map[string][]byte{}
+	}
+	return &file, nil
+}
+
+// write writes the token file atomically. The caller must hold s.mu.
+func (s *EncryptedFileTokenStore) write(file *encryptedTokenFile) error {
+	return jsonfile.Write(s.path, file)
+}
+
+// cipher returns the AEAD for the file, deriving it again only when the salt has changed.
+func (s *EncryptedFileTokenStore) cipher(file *encryptedTokenFile) (cipher.AEAD, error) {
+	if s.aead != nil && bytes.Equal(s.salt, file.Salt) {
+		return s.aead, nil
+	}
+	aead, err := s.key.cipher(file.Scrypt, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	s.aead = aead
+	s.salt = file.Salt
+	return aead, nil
+}
+
+// seal encrypts the token of the account.
+func seal(aead cipher.AEAD, account string, token *oauth2.Token) ([]byte, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(account)), nil
+}
+
+// open decrypts the token of the account.
+func open(aead cipher.AEAD, account string, sealed []byte) (*oauth2.Token, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(account))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
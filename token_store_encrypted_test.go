@@ -0,0 +1,75 @@
+package akerun
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh_secret", TokenType: "Bearer", Expiry: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	store, err := NewEncryptedFileTokenStore(path, PassphraseKey("correct horse"))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save("a", token))
+	assert.NoError(t, store.Save("b", token))
+
+	// Tokens are not stored in plain text
+	byt, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(byt, []byte("refresh_secret")))
+
+	loaded, err := store.Load("a")
+	assert.NoError(t, err)
+	assert.Equal(t, token.RefreshToken, loaded.RefreshToken)
+	assert.True(t, token.Expiry.Equal(loaded.Expiry))
+
+	// A new store with the wrong passphrase is rejected
+	_, err = NewEncryptedFileTokenStore(path, PassphraseKey("wrong"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Rotate to a key file
+	keyPath := filepath.Join(t.TempDir(), "token.key")
+	assert.NoError(t, GenerateKeyFile(keyPath))
+	assert.Error(t, GenerateKeyFile(keyPath))
+	key, err := KeyFromFile(keyPath)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Rotate(key))
+
+	_, err = NewEncryptedFileTokenStore(path, PassphraseKey("correct horse"))
+	assert.Error(t, err)
+	reopened, err := NewEncryptedFileTokenStore(path, key)
+	assert.NoError(t, err)
+	accounts, err := reopened.Accounts()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, accounts)
+	loaded, err = reopened.Load("b")
+	assert.NoError(t, err)
+	assert.Equal(t, token.RefreshToken, loaded.RefreshToken)
+
+	assert.NoError(t, reopened.Delete("a"))
+	_, err = reopened.Load("a")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestEncryptedFileTokenStore_TamperedAccount(t *testing.T) {
+	key, err := RawKey(bytes.Repeat([]byte{1}, 32))
+	assert.NoError(t, err)
+	store, err := NewEncryptedFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"), key)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save("a", &oauth2.Token{AccessToken: "access"}))
+
+	// A token moved to another account does not decrypt
+	file, err := store.read()
+	assert.NoError(t, err)
+	file.Tokens["b"] = file.Tokens["a"]
+	assert.NoError(t, store.write(file))
+	_, err = store.Load("b")
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
@@ -0,0 +1,241 @@
+package akerun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Default settings of TokenWatcher
+const (
+	DefaultRefreshBefore = 10 * time.Minute
+	DefaultCheckInterval = 5 * time.Minute
+)
+
+// TokenEventType represents the type of a TokenEvent.
+type TokenEventType string
+
+// Token event types
+const (
+	// TokenRefreshed is sent when a token has been refreshed ahead of its expiry.
+	TokenRefreshed TokenEventType = "refreshed"
+	// TokenRevoked is sent when the refresh token has been rejected as invalid_grant
+	// and the account must be authorized again.
+	TokenRevoked TokenEventType = "revoked"
+	// TokenCheckFailed is sent when a token could not be checked or refreshed for another reason.
+	TokenCheckFailed TokenEventType = "check_failed"
+)
+
+// TokenEvent represents a change of the state of an account's token.
+type TokenEvent struct {
+	Type    TokenEventType
+	Account string
+	// Token is the refreshed token of a TokenRefreshed event.
+	Token *oauth2.Token
+	// Err is the cause of a TokenRevoked or TokenCheckFailed event.
+	Err error
+}
+
+// TokenWatcher checks the tokens of a TokenManager in the background.
+// It refreshes tokens ahead of their expiry and reports revoked tokens,
+// so that API calls do not fail on an expired token.
+type TokenWatcher struct {
+	manager *TokenManager
+
+	// RefreshBefore is how long before the expiry a token is refreshed. If zero, DefaultRefreshBefore is used.
+	RefreshBefore time.Duration
+	// CheckInterval is the longest time between two checks of a token, which bounds how late a revocation is noticed.
+	// If zero, DefaultCheckInterval is used.
+	CheckInterval time.Duration
+	// OnEvent is called with every event. It must not block.
+	OnEvent func(TokenEvent)
+
+	now func() time.Time
+
+	mu sync.Mutex
+	// revoked holds the access token each account was last reported revoked for, so that it is reported only once.
+	revoked map[string]string
+}
+
+// NewTokenWatcher creates a new TokenWatcher for the accounts of manager.
+func NewTokenWatcher(manager *TokenManager, onEvent func(TokenEvent)) *TokenWatcher {
+	return &TokenWatcher{
+		manager: manager,
+		OnEvent: onEvent,
+		now:     time.Now,
+		revoked: map[string]string{},
+	}
+}
+
+// Run checks the tokens until ctx is canceled, waking up whenever a token is due to be refreshed.
+func (w *TokenWatcher) Run(ctx context.Context) error {
+	for {
+		next := w.Check(ctx)
+
+		timer := time.NewTimer(next.Sub(w.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Check checks the token of every account once and returns when the next check is due.
+func (w *TokenWatcher) Check(ctx context.Context) time.Time {
+	next := w.now().Add(w.checkInterval())
+
+	accounts, err := w.manager.Accounts()
+	if err != nil {
+		w.emit(TokenEvent{Type: TokenCheckFailed, Err: err})
+		return next
+	}
+	for _, account := range accounts {
+		if due := w.check(ctx, account); due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
+
+// check checks the token of the account and returns when it should be checked next.
+func (w *TokenWatcher) check(ctx context.Context, account string) time.Time {
+	now := w.now()
+	next := now.Add(w.checkInterval())
+
+	token, err := w.manager.storedToken(account)
+	if err != nil {
+		w.emit(TokenEvent{Type: TokenCheckFailed, Account: account, Err: err})
+		return next
+	}
+	if w.isRevoked(account, token) {
+		return next
+	}
+	if !token.Valid() {
+		// Refresh through the manager, so that the new token is stored.
+		return w.refresh(ctx, account, token, next)
+	}
+
+	info, err := w.manager.client.GetTokenInfo(ctx, token)
+	if err != nil {
+		if isUnauthorized(err) {
+			// The access token may just have expired without a known expiry,
+			// so only a failing refresh tells that the grant has been revoked.
+			return w.refresh(ctx, account, token, next)
+		}
+		w.emit(TokenEvent{Type: TokenCheckFailed, Account: account, Err: err})
+		return next
+	}
+
+	expires, err := info.Expires()
+	if err != nil {
+		w.emit(TokenEvent{Type: TokenCheckFailed, Account: account, Err: err})
+		return next
+	}
+	if token.Expiry.IsZero() {
+		// Let the manager refresh the token on demand too.
+		if err := w.manager.setExpiry(account, token, expires); err != nil {
+			w.emit(TokenEvent{Type: TokenCheckFailed, Account: account, Err: err})
+		}
+	}
+
+	due := expires.Add(-w.refreshBefore())
+	if !now.Before(due) {
+		return w.refresh(ctx, account, token, next)
+	}
+	if due.Before(next) {
+		return due
+	}
+	return next
+}
+
+// refresh refreshes the token of the account and reports the result.
+func (w *TokenWatcher) refresh(ctx context.Context, account string, token *oauth2.Token, next time.Time) time.Time {
+	newToken, err := w.manager.Refresh(ctx, account)
+	switch {
+	case err == nil:
+		w.emit(TokenEvent{Type: TokenRefreshed, Account: account, Token: newToken})
+		if !newToken.Expiry.IsZero() {
+			if due := newToken.Expiry.Add(-w.refreshBefore()); due.Before(next) && due.After(w.now()) {
+				return due
+			}
+		}
+	case isRevokedGrant(err):
+		w.markRevoked(account, token, err)
+	default:
+		w.emit(TokenEvent{Type: TokenCheckFailed, Account: account, Err: err})
+	}
+	return next
+}
+
+// markRevoked records and reports that the token of the account has been revoked.
+func (w *TokenWatcher) markRevoked(account string, token *oauth2.Token, err error) {
+	w.mu.Lock()
+	w.revoked[account] = token.AccessToken
+	w.mu.Unlock()
+	w.emit(TokenEvent{Type: TokenRevoked, Account: account, Err: err})
+}
+
+// isRevoked reports whether the token of the account has already been reported revoked.
+// A new token set for the account clears the state.
+func (w *TokenWatcher) isRevoked(account string, token *oauth2.Token) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	accessToken, ok := w.revoked[account]
+	if ok && accessToken != token.AccessToken {
+		delete(w.revoked, account)
+		return false
+	}
+	return ok
+}
+
+// emit passes the event to OnEvent.
+func (w *TokenWatcher) emit(event TokenEvent) {
+	if w.OnEvent != nil {
+		w.OnEvent(event)
+	}
+}
+
+// refreshBefore returns RefreshBefore or its default.
+func (w *TokenWatcher) refreshBefore() time.Duration {
+	if w.RefreshBefore > 0 {
+		return w.RefreshBefore
+	}
+	return DefaultRefreshBefore
+}
+
+// checkInterval returns CheckInterval or its default.
+func (w *TokenWatcher) checkInterval() time.Duration {
+	if w.CheckInterval > 0 {
+		return w.CheckInterval
+	}
+	return DefaultCheckInterval
+}
+
+// isUnauthorized reports whether err is a 401 response of the Akerun API.
+func isUnauthorized(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// isRevokedGrant reports whether err is a refresh failure that means the refresh token is no longer valid.
+// Other OAuth2 errors, such as invalid_client, point at the configuration rather than the token.
+func isRevokedGrant(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	return errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant"
+}
+
+// String returns a description of the event.
+func (e TokenEvent) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("token %s: account %s: %v", e.Type, e.Account, e.Err)
+	}
+	return fmt.Sprintf("token %s: account %s", e.Type, e.Account)
+}
@@ -0,0 +1,120 @@
+package akerun
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenInfo_Expires(t *testing.T) {
+	info := TokenInfo{CreatedAt: "2024-01-01T09:00:00+09:00", ExpiresAt: "2024-01-31T09:00:00+09:00"}
+	created, err := info.Created()
+	assert.NoError(t, err)
+	assert.True(t, created.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	expires, err := info.Expires()
+	assert.NoError(t, err)
+	assert.True(t, expires.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)))
+
+	_, err = (&TokenInfo{}).Expires()
+	assert.Error(t, err)
+}
+
+func TestTokenWatcher(t *testing.T) {
+	// oauth2.Token.Valid uses the wall clock, so the watcher must see it too
+	now := time.Now().Truncate(time.Second)
+	// Create a test server to mock the API and token responses
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/oauth/token/info":
+			switch r.Header.Get("Authorization") {
+			case "Bearer expiring":
+				body = `{"access_token":"expiring","expires_at":"` + now.Add(5*time.Minute).Format(time.RFC3339) + `"}`
+			case "Bearer fresh", "Bearer refreshed":
+				body = `{"access_token":"fresh","expires_at":"` + now.Add(time.Hour).Format(time.RFC3339) + `"}`
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				body = `{"error":"invalid_token"}`
+			}
+		case "/oauth/token":
+			assert.NoError(t, r.ParseForm())
+			w.Header().Set("Content-Type", "application/json")
+			switch r.PostForm.Get("refresh_token") {
+			case "refresh_a":
+				body = `{"access_token":"refreshed","refresh_token":"refresh_a2","token_type":"Bearer","expires_in":7200}`
+			case "refresh_d":
+				body = `{"access_token":"fresh","refresh_token":"refresh_d2","token_type":"Bearer","expires_in":7200}`
+			case "refresh_e":
+				w.WriteHeader(http.StatusUnauthorized)
+				body = `{"error":"invalid_client"}`
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				body = `{"error":"invalid_grant"}`
+			}
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+	originalTokenURL := os.Getenv("AKERUN_OAUTH2_TOKEN_URL")
+	os.Setenv("AKERUN_OAUTH2_TOKEN_URL", ts.URL+"/oauth/token")
+	defer os.Setenv("AKERUN_OAUTH2_TOKEN_URL", originalTokenURL)
+
+	client := NewClient(NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	store := NewMemoryTokenStore()
+	manager := NewTokenManager(client, store)
+	assert.NoError(t, manager.SetToken("a", &oauth2.Token{AccessToken: "expiring", RefreshToken: "refresh_a"}))
+	assert.NoError(t, manager.SetToken("b", &oauth2.Token{AccessToken: "fresh", RefreshToken: "refresh_b"}))
+	assert.NoError(t, manager.SetToken("c", &oauth2.Token{AccessToken: "revoked", RefreshToken: "refresh_c"}))
+	// An access token that expired without a known expiry is refreshed rather than reported revoked
+	assert.NoError(t, manager.SetToken("d", &oauth2.Token{AccessToken: "expired", RefreshToken: "refresh_d"}))
+	// A misconfigured client does not revoke the token
+	assert.NoError(t, manager.SetToken("e", &oauth2.Token{AccessToken: "expired", RefreshToken: "refresh_e"}))
+
+	var events []TokenEvent
+	w := NewTokenWatcher(manager, func(e TokenEvent) { events = append(events, e) })
+	w.now = func() time.Time { return now }
+
+	next := w.Check(context.Background())
+	assert.Len(t, events, 4)
+	assert.Equal(t, TokenRefreshed, events[0].Type)
+	assert.Equal(t, "a", events[0].Account)
+	assert.Equal(t, "refreshed", events[0].Token.AccessToken)
+	assert.Equal(t, TokenRevoked, events[1].Type)
+	assert.Equal(t, "c", events[1].Account)
+	assert.Equal(t, TokenRefreshed, events[2].Type)
+	assert.Equal(t, "d", events[2].Account)
+	assert.Equal(t, TokenCheckFailed, events[3].Type)
+	assert.Equal(t, "e", events[3].Account)
+	// The fresh token is checked again on the regular interval
+	assert.Equal(t, now.Add(DefaultCheckInterval), next)
+
+	stored, err := store.Load("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh_a2", stored.RefreshToken)
+	// The expiry from the token information is kept for on-demand refreshes
+	stored, err = store.Load("b")
+	assert.NoError(t, err)
+	assert.True(t, stored.Expiry.Equal(now.Add(time.Hour)))
+
+	// A revoked token is reported only once, while other failures are retried
+	events = nil
+	w.Check(context.Background())
+	assert.Len(t, events, 1)
+	assert.Equal(t, TokenCheckFailed, events[0].Type)
+	assert.Equal(t, "e", events[0].Account)
+}
@@ -18,6 +18,8 @@ type UsersList struct {
 type NFC struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
 }
 
 type User struct {
@@ -30,10 +32,8 @@ type User struct {
 	CreatedAt string `json:"created_at,omitempty"`
 	UpdatedAt string `json:"updated_at,omitempty"`
 	Nfcs      []NFC  `json:"nfcs"`
-}
-
-type userRow struct {
-	User User `json:"user"`
+	// Extra holds the fields unknown to this version of the client.
+	Extra Extra `json:"-"`
 }
 
 type UsersParameter struct {
@@ -56,7 +56,7 @@ func (c *Client) GetUsers(
 	if err != nil {
 		return nil, err
 	}
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathUsers), http.MethodGet, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpGetUsers, path.Join(apiPathOrganizations, organizationId, apiPathUsers), http.MethodGet, oauth2Token, v, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -69,12 +69,12 @@ func (c *Client) GetUser(
 	organizationId string,
 	userId string,
 ) (*User, error) {
-	var result userRow
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodGet, oauth2Token, nil, nil, &result)
+	var result User
+	err := c.callVersion(ctx, OpGetUser, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodGet, oauth2Token, nil, enveloped("user", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.User, nil
+	return &result, nil
 }
 
 type RegisterUserParameter struct {
@@ -91,17 +91,17 @@ func (c *Client) RegisterUser(
 	name string,
 	params RegisterUserParameter,
 ) (*User, error) {
-	var result userRow
+	var result User
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 	v.Add("user_name", name)
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathUsers), http.MethodPost, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpRegisterUser, path.Join(apiPathOrganizations, organizationId, apiPathUsers), http.MethodPost, oauth2Token, v, enveloped("user", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.User, nil
+	return &result, nil
 }
 
 type InviteUserParameter struct {
@@ -117,17 +117,17 @@ func (c *Client) InviteUser(
 	userId string,
 	params InviteUserParameter,
 ) (*User, error) {
-	var result userRow
+	var result User
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 	v.Add("user_id", userId)
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodPost, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpInviteUser, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodPost, oauth2Token, v, enveloped("user", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.User, nil
+	return &result, nil
 }
 
 type UpdateUserParameter struct {
@@ -145,17 +145,17 @@ func (c *Client) UpdateUser(
 	userId string,
 	params UpdateUserParameter,
 ) (*User, error) {
-	var result userRow
+	var result User
 	v, err := query.Values(params)
 	if err != nil {
 		return nil, err
 	}
 	v.Add("user_id", userId)
-	err = c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodPut, oauth2Token, v, nil, &result)
+	err = c.callVersion(ctx, OpUpdateUser, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodPut, oauth2Token, v, enveloped("user", &result))
 	if err != nil {
 		return nil, err
 	}
-	return &result.User, nil
+	return &result, nil
 }
 
 func (c *Client) ExitUser(
@@ -165,7 +165,7 @@ func (c *Client) ExitUser(
 	userId string,
 ) error {
 
-	err := c.callVersion(ctx, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodDelete, oauth2Token, nil, nil, nil)
+	err := c.callVersion(ctx, OpExitUser, path.Join(apiPathOrganizations, organizationId, apiPathUsers, userId), http.MethodDelete, oauth2Token, nil, nil)
 	if err != nil {
 		return err
 	}
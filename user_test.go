@@ -85,7 +85,8 @@ func TestClient_RegisterUser(t *testing.T) {
 		assert.Equal(t, "/v3/organizations/org1/users", r.URL.Path)
 
 		// Write a sample response
-		q := r.URL.Query()
+		assert.NoError(t, r.ParseForm())
+		q := r.PostForm
 		userName := q.Get("user_name")
 		userEmail := q.Get("user_mail")
 		_, err := w.Write([]byte(fmt.Sprintf(`{"user":{"id":"user1","name":"%s","mail":"%s"}}`, userName, userEmail)))
@@ -123,7 +124,8 @@ func TestClient_UpdateUser(t *testing.T) {
 		assert.Equal(t, "/v3/organizations/org1/users/user1", r.URL.Path)
 
 		// Write a sample response
-		q := r.URL.Query()
+		assert.NoError(t, r.ParseForm())
+		q := r.PostForm
 		userName := q.Get("user_name")
 		userEmail := q.Get("user_mail")
 		_, err := w.Write([]byte(fmt.Sprintf(`{"user":{"id":"user1","name":"%s","mail":"%s"}}`, userName, userEmail)))
@@ -186,7 +188,8 @@ func TestClient_InviteUser(t *testing.T) {
 		assert.Equal(t, "/v3/organizations/org1/users/user1", r.URL.Path)
 
 		// Write a sample response
-		q := r.URL.Query()
+		assert.NoError(t, r.ParseForm())
+		q := r.PostForm
 		userId := q.Get("user_id")
 		_, err := w.Write([]byte(fmt.Sprintf(`{"user":{"id":"%s","name":"Test User"}}`, userId)))
 		if err != nil {
@@ -0,0 +1,46 @@
+package visitor
+
+import (
+	"sync"
+
+	"github.com/Hayao0819/go-akerun/internal/jsonfile"
+)
+
+// Store represents a persistent storage for visits.
+type Store interface {
+	// Load returns the stored visits. It returns no visits if nothing has been saved yet.
+	Load() ([]Visit, error)
+	// Save replaces the stored visits.
+	Save(visits []Visit) error
+}
+
+// FileStore is a Store that keeps the visits in a JSON file.
+// The file is not locked, so it must not be written by more than one process at a time.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a new FileStore that reads and writes the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the visits from the file.
+func (s *FileStore) Load() ([]Visit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var visits []Visit
+	if _, err := jsonfile.Read(s.path, &visits); err != nil {
+		return nil, err
+	}
+	return visits, nil
+}
+
+// Save writes the visits to the file atomically.
+func (s *FileStore) Save(visits []Visit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return jsonfile.Write(s.path, visits)
+}
@@ -0,0 +1,417 @@
+// Package visitor issues short-lived Akerun access for guests and revokes it when their visit ends.
+package visitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"golang.org/x/oauth2"
+)
+
+// Visit statuses
+const (
+	// StatusPending is a visit whose registration has not finished.
+	// Cleanup revokes what it holds once it has been pending for longer than the pending timeout.
+	StatusPending = "pending"
+	StatusActive  = "active"
+	StatusEnded   = "ended"
+)
+
+// DefaultPendingTimeout is the default time after which Cleanup considers a pending registration abandoned.
+const DefaultPendingTimeout = 10 * time.Minute
+
+// Errors returned by Manager
+var (
+	ErrVisitNotFound = errors.New("visitor: visit not found")
+	// ErrVisitBusy is returned when a visit is being registered or ended by another call.
+	ErrVisitBusy = errors.New("visitor: visit is being updated")
+)
+
+// Visit represents a recorded visit.
+type Visit struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	UserID         string    `json:"user_id"`
+	Name           string    `json:"name"`
+	Mail           string    `json:"mail,omitempty"`
+	AkerunIDs      []string  `json:"akerun_ids"`
+	KeyIDs         []string  `json:"key_ids"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Status         string    `json:"status"`
+	// RegisteredAt is when the registration of the visit started.
+	RegisteredAt time.Time `json:"registered_at"`
+	EndedAt      time.Time `json:"ended_at,omitempty"`
+	// LastError is the error of the last failed cleanup attempt.
+	LastError string `json:"last_error,omitempty"`
+	// KeyURLs open the doors for whoever holds them, so they are only returned by Register and never stored.
+	KeyURLs []string `json:"-"`
+}
+
+// Request represents a visit to register.
+type Request struct {
+	Name string
+	Mail string
+	// AkerunIDs and the Akeruns of AkerunGroupID are the doors the visitor can open.
+	AkerunIDs     []string
+	AkerunGroupID string
+	Start         time.Time
+	End           time.Time
+	// EnableKeyURL issues key URLs, optionally protected by KeyURLPassword.
+	EnableKeyURL   bool
+	KeyURLPassword string
+}
+
+// Manager registers visitors and cleans up after their visits.
+// It is safe for concurrent use, but it assumes that it is the only writer of its store:
+// Managers in other processes that write the same file at the same time may lose each other's updates.
+type Manager struct {
+	client         *akerun.Client
+	token          *oauth2.Token
+	organizationId string
+	store          Store
+	now            func() time.Time
+	pendingTimeout time.Duration
+
+	// mu guards the store and busy, and is not held while the Akerun API is called.
+	mu sync.Mutex
+	// busy holds the IDs of the visits that are being registered or ended.
+	busy map[string]bool
+}
+
+// NewManager creates a new Manager for the organization.
+func NewManager(client *akerun.Client, token *oauth2.Token, organizationId string, store Store) *Manager {
+	return &Manager{
+		client:         client,
+		token:          token,
+		organizationId: organizationId,
+		store:          store,
+		now:            time.Now,
+		pendingTimeout: DefaultPendingTimeout,
+		busy:           map[string]bool{},
+	}
+}
+
+// SetPendingTimeout sets the time after which Cleanup revokes a registration that has not finished.
+// It must be longer than any registration takes, or Cleanup may revoke visits that are still being registered.
+func (m *Manager) SetPendingTimeout(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pendingTimeout = d
+}
+
+// Register registers the visitor as a user, issues temporary keys for the requested Akeruns and records the visit.
+// The visit is recorded as pending before anything is created and updated as each key is issued,
+// so that a registration that is interrupted can be revoked by Cleanup.
+// If any step fails, the keys and user created so far are removed again.
+func (m *Manager) Register(ctx context.Context, req Request) (*Visit, error) {
+	if !req.End.After(req.Start) {
+		return nil, fmt.Errorf("visitor: end %v is not after start %v", req.End, req.Start)
+	}
+
+	akeruns, err := m.akeruns(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(akeruns) == 0 {
+		return nil, errors.New("visitor: no Akeruns requested")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	visit := Visit{
+		ID:             id,
+		OrganizationID: m.organizationId,
+		Name:           req.Name,
+		Mail:           req.Mail,
+		AkerunIDs:      akeruns,
+		Start:          req.Start,
+		End:            req.End,
+		Status:         StatusPending,
+		RegisteredAt:   m.now(),
+	}
+	m.mu.Lock()
+	m.busy[id] = true
+	err = m.put(visit)
+	m.mu.Unlock()
+	defer m.release(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := m.client.RegisterUser(ctx, m.token, m.organizationId, req.Name, akerun.RegisterUserParameter{UserMail: req.Mail})
+	if err != nil {
+		return nil, errors.Join(err, m.abort(ctx, &visit))
+	}
+	visit.UserID = user.ID
+	if err := m.update(visit); err != nil {
+		return nil, errors.Join(err, m.abort(ctx, &visit))
+	}
+
+	for _, akerunId := range akeruns {
+		params := akerun.CreateKeyParameter{
+			ScheduleType:   akerun.ScheduleTypeTemporary,
+			EnableKeyUrl:   req.EnableKeyURL,
+			KeyUrlPassword: req.KeyURLPassword,
+		}
+		params.TemporarySchedule.StartDateTime = req.Start.Format(time.RFC3339)
+		params.TemporarySchedule.EndDateTime = req.End.Format(time.RFC3339)
+
+		key, err := m.client.CreateKey(ctx, m.token, m.organizationId, user.ID, akerunId, params)
+		if err != nil {
+			return nil, errors.Join(err, m.abort(ctx, &visit))
+		}
+		visit.KeyIDs = append(visit.KeyIDs, key.ID)
+		if key.Keys.KeyUrl != "" {
+			visit.KeyURLs = append(visit.KeyURLs, key.Keys.KeyUrl)
+		}
+		if err := m.update(visit); err != nil {
+			return nil, errors.Join(err, m.abort(ctx, &visit))
+		}
+	}
+
+	visit.Status = StatusActive
+	if err := m.update(visit); err != nil {
+		return nil, errors.Join(err, m.abort(ctx, &visit))
+	}
+	return &visit, nil
+}
+
+// abort revokes what a failed registration created and records the outcome.
+// If revoking fails too, the visit stays pending for Cleanup.
+func (m *Manager) abort(ctx context.Context, visit *Visit) error {
+	// The registration may have failed because ctx was canceled, which must not stop the revocation.
+	err := m.end(context.WithoutCancel(ctx), visit)
+	return errors.Join(err, m.update(*visit))
+}
+
+// update stores visit, replacing the stored visit with the same ID.
+func (m *Manager) update(visit Visit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.put(visit)
+}
+
+// release marks the visit as no longer being registered or ended.
+func (m *Manager) release(visitId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.busy, visitId)
+}
+
+// put stores visit without its key URLs, replacing the stored visit with the same ID. The caller must hold m.mu.
+func (m *Manager) put(visit Visit) error {
+	visit.KeyURLs = nil
+	visits, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+	for i := range visits {
+		if visits[i].ID == visit.ID {
+			visits[i] = visit
+			return m.store.Save(visits)
+		}
+	}
+	return m.store.Save(append(visits, visit))
+}
+
+// akeruns returns the IDs of the requested Akeruns without duplicates.
+func (m *Manager) akeruns(ctx context.Context, req Request) ([]string, error) {
+	ids := append([]string{}, req.AkerunIDs...)
+	if req.AkerunGroupID != "" {
+		group, err := m.client.GetAkerunGroup(ctx, m.token, m.organizationId, req.AkerunGroupID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range group.Akeruns {
+			ids = append(ids, a.ID)
+		}
+	}
+
+	seen := map[string]bool{}
+	var unique []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// Visits returns the recorded visits.
+func (m *Manager) Visits() ([]Visit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.Load()
+}
+
+// End deletes the keys of a visit, removes the visitor from the organization and marks the visit as ended.
+// It returns ErrVisitBusy if the visit is being registered or ended by another call.
+func (m *Manager) End(ctx context.Context, visitId string) error {
+	visit, err := m.acquire(visitId)
+	if err != nil {
+		return err
+	}
+	defer m.release(visitId)
+
+	err = m.end(ctx, visit)
+	return errors.Join(err, m.update(*visit))
+}
+
+// acquire returns the stored visit with the ID and marks it as being ended.
+func (m *Manager) acquire(visitId string) (*Visit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	visits, err := m.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range visits {
+		if visits[i].ID == visitId {
+			if m.busy[visitId] {
+				return nil, ErrVisitBusy
+			}
+			m.busy[visitId] = true
+			return &visits[i], nil
+		}
+	}
+	return nil, ErrVisitNotFound
+}
+
+// Cleanup ends every active visit whose end time has passed, and every pending visit left behind by
+// an interrupted registration that started longer than the pending timeout ago, and returns the visits it ended.
+// Visits that fail to clean up keep their status and are retried on the next call.
+// Visits that are being registered or ended by another call are left to it.
+func (m *Manager) Cleanup(ctx context.Context) ([]Visit, error) {
+	due, err := m.acquireDue()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ended []Visit
+		errs  []error
+	)
+	for i := range due {
+		err := m.end(ctx, &due[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("visit %s: %w", due[i].ID, err))
+		}
+		errs = append(errs, m.update(due[i]))
+		m.release(due[i].ID)
+		if err == nil {
+			ended = append(ended, due[i])
+		}
+	}
+	return ended, errors.Join(errs...)
+}
+
+// acquireDue returns the stored visits that Cleanup has to end and marks them as being ended.
+func (m *Manager) acquireDue() ([]Visit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	visits, err := m.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	var due []Visit
+	now := m.now()
+	for _, v := range visits {
+		expired := v.Status == StatusActive && !now.Before(v.End)
+		abandoned := v.Status == StatusPending && !now.Before(v.RegisteredAt.Add(m.pendingTimeout))
+		if (!expired && !abandoned) || m.busy[v.ID] {
+			continue
+		}
+		m.busy[v.ID] = true
+		due = append(due, v)
+	}
+	return due, nil
+}
+
+// Run calls Cleanup every interval until ctx is canceled. It returns an error if interval is not positive.
+// Cleanup errors are passed to onError if it is not nil.
+func (m *Manager) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("visitor: interval must be positive, got %v", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Cleanup(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// end revokes the access of a visit and updates its status.
+func (m *Manager) end(ctx context.Context, visit *Visit) error {
+	if err := m.revoke(ctx, visit); err != nil {
+		visit.LastError = err.Error()
+		return err
+	}
+	visit.Status = StatusEnded
+	visit.EndedAt = m.now()
+	visit.LastError = ""
+	return nil
+}
+
+// revoke deletes the keys of a visit and removes the visitor from the organization.
+// Keys and users that no longer exist are ignored.
+func (m *Manager) revoke(ctx context.Context, visit *Visit) error {
+	var remaining []string
+	var errs []error
+	for _, keyId := range visit.KeyIDs {
+		if err := m.client.DeleteKey(ctx, m.token, visit.OrganizationID, keyId); err != nil && !isNotFound(err) {
+			remaining = append(remaining, keyId)
+			errs = append(errs, err)
+		}
+	}
+	visit.KeyIDs = remaining
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// A registration may have been interrupted before the user was created.
+	if visit.UserID == "" {
+		return nil
+	}
+	if err := m.client.ExitUser(ctx, m.token, visit.OrganizationID, visit.UserID); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isNotFound reports whether err is a 404 response of the Akerun API.
+func isNotFound(err error) bool {
+	var apiErr *akerun.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// newID returns a random visit ID.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
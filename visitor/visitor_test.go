@@ -0,0 +1,330 @@
+package visitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Hayao0819/go-akerun"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestManager(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		var body string
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/organizations/org1/akerun_groups/G1":
+			body = `{"akerun_group":{"id":"G1","akeruns":[{"id":"A1"},{"id":"A2"}]}}`
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/users":
+			assert.Equal(t, "Guest", r.PostFormValue("user_name"))
+			body = `{"user":{"id":"guest1","name":"Guest"}}`
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/keys":
+			assert.Equal(t, "temporary", r.PostFormValue("schedule_type"))
+			assert.Equal(t, "secret", r.PostFormValue("key_url_password"))
+			akerunId := r.PostFormValue("akerun_id")
+			body = `{"key":{"id":"key-` + akerunId + `","keys":{"key_url":"https://example.com/` + akerunId + `","password_protected":true}}}`
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "visits.json"))
+	m := NewManager(client, token, "org1", store)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	visit, err := m.Register(context.Background(), Request{
+		Name:           "Guest",
+		AkerunIDs:      []string{"A1"},
+		AkerunGroupID:  "G1",
+		Start:          start,
+		End:            start.Add(2 * time.Hour),
+		EnableKeyURL:   true,
+		KeyURLPassword: "secret",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "guest1", visit.UserID)
+	assert.Equal(t, []string{"A1", "A2"}, visit.AkerunIDs)
+	assert.Equal(t, []string{"key-A1", "key-A2"}, visit.KeyIDs)
+	assert.Len(t, visit.KeyURLs, 2)
+
+	// Key URLs are not written to the store
+	data, err := os.ReadFile(filepath.Join(dir, "visits.json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "https://example.com/")
+	visits, err := m.Visits()
+	assert.NoError(t, err)
+	assert.Empty(t, visits[0].KeyURLs)
+
+	// Nothing is cleaned up before the visit ends
+	m.now = func() time.Time { return start.Add(time.Hour) }
+	ended, err := m.Cleanup(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, ended)
+
+	// After the visit, the keys are deleted and the guest exits
+	requests = nil
+	m.now = func() time.Time { return start.Add(3 * time.Hour) }
+	ended, err = m.Cleanup(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, ended, 1)
+	assert.Equal(t, []string{
+		"DELETE /v3/organizations/org1/keys/key-A1",
+		"DELETE /v3/organizations/org1/keys/key-A2",
+		"DELETE /v3/organizations/org1/users/guest1",
+	}, requests)
+
+	visits, err = m.Visits()
+	assert.NoError(t, err)
+	assert.Len(t, visits, 1)
+	assert.Equal(t, StatusEnded, visits[0].Status)
+	assert.Empty(t, visits[0].KeyIDs)
+
+	// A zero interval is rejected instead of making the ticker panic
+	assert.Error(t, m.Run(context.Background(), 0, nil))
+}
+
+func TestManager_PendingVisit(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+		healthy  bool
+	)
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		ok := healthy
+		mu.Unlock()
+
+		var body string
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/users":
+			body = `{"user":{"id":"guest1","name":"Guest"}}`
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/keys":
+			if r.PostFormValue("akerun_id") == "A2" {
+				w.WriteHeader(http.StatusInternalServerError)
+				body = `{"message":"internal error"}`
+				break
+			}
+			body = `{"key":{"id":"key-A1"}}`
+		case r.Method == http.MethodDelete && r.URL.Path == "/v3/organizations/org1/users/guest1" && !ok:
+			w.WriteHeader(http.StatusInternalServerError)
+			body = `{"message":"internal error"}`
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	store := NewFileStore(filepath.Join(t.TempDir(), "visits.json"))
+	m := NewManager(client, token, "org1", store)
+
+	// The second key fails and so does removing the guest, so the visit is left pending
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return start.Add(-time.Hour) }
+	_, err := m.Register(context.Background(), Request{
+		Name:      "Guest",
+		AkerunIDs: []string{"A1", "A2"},
+		Start:     start,
+		End:       start.Add(2 * time.Hour),
+	})
+	assert.Error(t, err)
+
+	visits, err := m.Visits()
+	assert.NoError(t, err)
+	assert.Len(t, visits, 1)
+	assert.Equal(t, StatusPending, visits[0].Status)
+	assert.Equal(t, "guest1", visits[0].UserID)
+	assert.Empty(t, visits[0].KeyIDs)
+	assert.NotEmpty(t, visits[0].LastError)
+
+	// A registration that may still be in progress elsewhere is left alone
+	mu.Lock()
+	requests = nil
+	healthy = true
+	mu.Unlock()
+	m.now = func() time.Time { return start.Add(-time.Hour + time.Minute) }
+	ended, err := m.Cleanup(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, ended)
+	assert.Empty(t, requests)
+
+	// Cleanup finishes revoking the abandoned visit before its end time
+	m.now = func() time.Time { return start }
+	ended, err = m.Cleanup(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, ended, 1)
+	assert.Equal(t, []string{"DELETE /v3/organizations/org1/users/guest1"}, requests)
+
+	visits, err = m.Visits()
+	assert.NoError(t, err)
+	assert.Equal(t, StatusEnded, visits[0].Status)
+
+	// A visit recorded before the user was created is ended without calling the API
+	assert.NoError(t, store.Save([]Visit{{ID: "v2", OrganizationID: "org1", Status: StatusPending}}))
+	requests = nil
+	ended, err = m.Cleanup(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, ended, 1)
+	assert.Empty(t, requests)
+}
+
+func TestManager_ConcurrentRegister(t *testing.T) {
+	keyRequested := make(chan struct{})
+	unblock := make(chan struct{})
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/users":
+			body = `{"user":{"id":"guest1","name":"Guest"}}`
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/keys":
+			close(keyRequested)
+			<-unblock
+			body = `{"key":{"id":"key-A1"}}`
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	m := NewManager(client, token, "org1", NewFileStore(filepath.Join(t.TempDir(), "visits.json")))
+	m.SetPendingTimeout(0)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	registered := make(chan error)
+	go func() {
+		_, err := m.Register(context.Background(), Request{Name: "Guest", AkerunIDs: []string{"A1"}, Start: start, End: start.Add(time.Hour)})
+		registered <- err
+	}()
+	<-keyRequested
+
+	// The manager stays usable while a registration waits for the API,
+	// and the visit being registered is left to it
+	visits, err := m.Visits()
+	assert.NoError(t, err)
+	assert.Len(t, visits, 1)
+	ended, err := m.Cleanup(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, ended)
+	assert.ErrorIs(t, m.End(context.Background(), visits[0].ID), ErrVisitBusy)
+
+	close(unblock)
+	assert.NoError(t, <-registered)
+	visits, err = m.Visits()
+	assert.NoError(t, err)
+	assert.Equal(t, StatusActive, visits[0].Status)
+}
+
+func TestManager_CanceledRegister(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	// Create a test server to mock the API response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		var body string
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/users":
+			body = `{"user":{"id":"guest1","name":"Guest"}}`
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/organizations/org1/keys":
+			// The caller gives up while the key is being created
+			assert.NoError(t, r.ParseForm())
+			cancel()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+				t.Error("the request was not canceled")
+			}
+			return
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer ts.Close()
+
+	originalValue := os.Getenv("AKERUN_API_URL")
+	os.Setenv("AKERUN_API_URL", ts.URL)
+	defer os.Setenv("AKERUN_API_URL", originalValue)
+
+	client := akerun.NewClient(akerun.NewConfig("testId", "testPass", "http://localhost:8080/callback"))
+	token := &oauth2.Token{AccessToken: "test_token"}
+	m := NewManager(client, token, "org1", NewFileStore(filepath.Join(t.TempDir(), "visits.json")))
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	_, err := m.Register(ctx, Request{Name: "Guest", AkerunIDs: []string{"A1"}, Start: start, End: start.Add(time.Hour)})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The guest is still removed
+	mu.Lock()
+	assert.Contains(t, requests, "DELETE /v3/organizations/org1/users/guest1")
+	mu.Unlock()
+	visits, err := m.Visits()
+	assert.NoError(t, err)
+	assert.Equal(t, StatusEnded, visits[0].Status)
+}